@@ -0,0 +1,67 @@
+package env
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompletionUsage returns a function that writes a shell completion script for the environment variables,
+// for the given shell: bash, zsh, or fish. It can be used as [Options.UsageFunc].
+// The script completes the variable names as arguments of the env(1) command,
+// e.g. `env DB_<TAB>` when launching the application as `env DB_HOST=localhost app`.
+// An unknown shell causes a panic.
+func CompletionUsage(shell string) func(vars []Var, w io.Writer, opts *Options) {
+	switch shell {
+	case "bash":
+		return bashCompletion
+	case "zsh":
+		return zshCompletion
+	case "fish":
+		return fishCompletion
+	default:
+		panic(fmt.Sprintf("env: unsupported shell `%s`", shell))
+	}
+}
+
+func bashCompletion(vars []Var, w io.Writer, _ *Options) {
+	names := make([]string, 0, len(vars))
+	for _, v := range vars {
+		names = append(names, v.Name+"=")
+	}
+	fmt.Fprintf(w, "_env_vars() {\n")
+	fmt.Fprintf(w, "\tCOMPREPLY=($(compgen -W %s -- \"${COMP_WORDS[COMP_CWORD]}\"))\n", shellQuote(strings.Join(names, " ")))
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -o nospace -F _env_vars env\n")
+}
+
+func zshCompletion(vars []Var, w io.Writer, _ *Options) {
+	fmt.Fprintf(w, "_env_vars() {\n")
+	fmt.Fprintf(w, "\tlocal -a vars=(\n")
+	for _, v := range vars {
+		desc := v.Usage
+		if desc == "" {
+			desc = v.Type.String()
+		}
+		fmt.Fprintf(w, "\t\t%s\n", shellQuote(v.Name+":"+desc))
+	}
+	fmt.Fprintf(w, "\t)\n")
+	fmt.Fprintf(w, "\t_describe 'environment variable' vars -S '='\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "compdef _env_vars env\n")
+}
+
+func fishCompletion(vars []Var, w io.Writer, _ *Options) {
+	for _, v := range vars {
+		desc := v.Usage
+		if desc == "" {
+			desc = v.Type.String()
+		}
+		fmt.Fprintf(w, "complete -c env -f -a %s -d %s\n", shellQuote(v.Name+"="), shellQuote(desc))
+	}
+}
+
+// shellQuote quotes s with single quotes, which is understood by all supported shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
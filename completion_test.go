@@ -0,0 +1,50 @@
+package env_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestCompletionUsage(t *testing.T) {
+	var cfg struct {
+		Host string `env:"DB_HOST" usage:"database host"`
+		Port int    `env:"DB_PORT"`
+	}
+
+	tests := map[string]string{
+		"bash": `_env_vars() {
+	COMPREPLY=($(compgen -W 'DB_HOST= DB_PORT=' -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -o nospace -F _env_vars env
+`,
+		"zsh": `_env_vars() {
+	local -a vars=(
+		'DB_HOST:database host'
+		'DB_PORT:int'
+	)
+	_describe 'environment variable' vars -S '='
+}
+compdef _env_vars env
+`,
+		"fish": `complete -c env -f -a 'DB_HOST=' -d 'database host'
+complete -c env -f -a 'DB_PORT=' -d 'int'
+`,
+	}
+
+	for shell, want := range tests {
+		t.Run(shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			env.Usage(&cfg, &buf, &env.Options{UsageFunc: env.CompletionUsage(shell)})
+			assert.Equal[E](t, buf.String(), want)
+		})
+	}
+
+	t.Run("unsupported shell", func(t *testing.T) {
+		completion := func() { env.CompletionUsage("cmd") }
+		assert.Panics[E](t, completion, "env: unsupported shell `cmd`")
+	})
+}
@@ -0,0 +1,324 @@
+package env
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File returns a [Map] with the variables parsed from the .env file at path.
+// The following syntax is supported:
+//
+//	# comments and empty lines are ignored
+//	export KEY=value        # the export prefix and inline comments are ignored
+//	SINGLE='literal $value' # single-quoted values are taken as is
+//	DOUBLE="a\tb\n"         # double-quoted values support the \n, \r, \t, \", \\, and \$ escapes
+//	MULTILINE="first line
+//	second line"            # quoted values can span multiple lines
+//
+// The values are not expanded; use the `expand` option or [Options.ExpandAll] for that.
+// Use [IgnoreMissing] for the optional files, e.g. .env.local.
+func File(path string) (Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("env: reading file: %w", err)
+	}
+	m, err := parseDotenv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("env: %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// DecryptFile is like [File], but decrypts the contents of the file with the given function before parsing them,
+// so that secrets can be committed encrypted. The package does not depend on any encryption library,
+// e.g. for age (filippo.io/age):
+//
+//	src, err := env.DecryptFile(".env.age", func(data []byte) ([]byte, error) {
+//		r, err := age.Decrypt(bytes.NewReader(data), identity)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return io.ReadAll(r)
+//	})
+//
+// or for SOPS (github.com/getsops/sops/v3/decrypt):
+//
+//	src, err := env.DecryptFile(".env.enc", func(data []byte) ([]byte, error) {
+//		return decrypt.Data(data, "dotenv")
+//	})
+func DecryptFile(path string, decrypt func(data []byte) ([]byte, error)) (Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("env: reading file: %w", err)
+	}
+	plaintext, err := decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("env: %s: decrypting: %w", path, err)
+	}
+	m, err := parseDotenv(string(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("env: %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// parseDotenv parses the .env syntax, see [File].
+func parseDotenv(s string) (Map, error) {
+	m := make(Map)
+	if err := scanDotenv(s, func(p dotenvPair) { m[p.key] = p.value }); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// dotenvPair is a KEY=VALUE pair found by scanDotenv.
+type dotenvPair struct {
+	key, value string
+	exported   bool // the pair has the export prefix.
+	start, end int  // the offsets of the pair in the input, from the key (or the export prefix) to the end of its last line.
+}
+
+// scanDotenv calls fn for each pair in the .env syntax, see [File].
+func scanDotenv(s string, fn func(p dotenvPair)) error {
+	input := s
+	offset := func() int { return len(input) - len(s) }
+	line := 1
+	for len(s) > 0 {
+		// skip the leading whitespace, empty lines, and comments.
+		switch c := s[0]; {
+		case c == '\n':
+			line++
+			s = s[1:]
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			s = s[1:]
+			continue
+		case c == '#':
+			s = skipLine(s)
+			continue
+		}
+
+		start := offset()
+		rest, exported := strings.CutPrefix(s, "export ")
+		s = rest
+		i := strings.IndexAny(s, "=\n")
+		if i < 0 || s[i] != '=' || strings.TrimSpace(s[:i]) == "" {
+			return fmt.Errorf("line %d: invalid KEY=VALUE pair", line)
+		}
+		key := strings.TrimSpace(s[:i])
+		s = strings.TrimLeft(s[i+1:], " \t")
+
+		var value string
+		var err error
+		startLine := line
+		if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+			var rest string
+			value, rest, err = parseQuoted(s)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", startLine, err)
+			}
+			line += strings.Count(s[:len(s)-len(rest)], "\n")
+			if trimmed := strings.TrimLeft(rest, " \t\r"); trimmed != "" && trimmed[0] != '\n' && trimmed[0] != '#' {
+				return fmt.Errorf("line %d: unexpected characters after the quoted value", startLine)
+			}
+			s = skipLine(rest)
+		} else {
+			end := strings.IndexByte(s, '\n')
+			if end < 0 {
+				end = len(s)
+			}
+			value = s[:end]
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = value[:j]
+			}
+			value = strings.TrimSpace(value)
+			s = s[end:]
+		}
+		fn(dotenvPair{key: key, value: value, exported: exported, start: start, end: offset()})
+	}
+	return nil
+}
+
+// quoteDotenv returns the value in the .env syntax, double-quoting and escaping it if needed, see [File].
+func quoteDotenv(value string) string {
+	if !strings.ContainsAny(value, " \t\r\n#'\"\\$") {
+		return value
+	}
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '"', '\\', '$':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// parseQuoted parses a single- or double-quoted value and returns it along with the rest of the input.
+func parseQuoted(s string) (value, rest string, err error) {
+	quote := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return sb.String(), s[i+1:], nil
+		case c == '\\' && quote == '"' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\', '$':
+				sb.WriteByte(s[i])
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", "", errors.New("unterminated quoted value")
+}
+
+// skipLine returns s without the current line, keeping the newline.
+func skipLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[i:]
+	}
+	return ""
+}
+
+// UpdateFile sets the given variables in the .env file at path, preserving comments, ordering, and other variables.
+// The existing pairs are updated in place (keeping the optional `export` prefix), and the new ones are appended in sorted order.
+// The values are quoted and escaped if needed, so that the file can be read back with [File].
+// The file is created if it does not exist and replaced atomically otherwise.
+// Values must not contain newlines.
+func UpdateFile(path string, values Map) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if strings.ContainsAny(key, "=\n") || strings.TrimSpace(key) != key || key == "" {
+			return fmt.Errorf("env: invalid key %q", key)
+		}
+		if strings.Contains(values[key], "\n") {
+			return fmt.Errorf("env: %s: values must not contain newlines", key)
+		}
+	}
+
+	mode := fs.FileMode(0o600)
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("env: reading file: %w", err)
+	default:
+		if fi, err := os.Stat(path); err == nil {
+			mode = fi.Mode().Perm()
+		}
+	}
+
+	var buf bytes.Buffer
+	updated := make(map[string]bool, len(values))
+	src, last := string(data), 0
+	err = scanDotenv(src, func(p dotenvPair) {
+		value, ok := values[p.key]
+		if !ok {
+			return
+		}
+		buf.WriteString(src[last:p.start])
+		if p.exported {
+			buf.WriteString("export ")
+		}
+		fmt.Fprintf(&buf, "%s=%s", p.key, quoteDotenv(value))
+		last = p.end
+		updated[p.key] = true
+	})
+	if err != nil {
+		return fmt.Errorf("env: %s: %w", path, err)
+	}
+	buf.WriteString(src[last:])
+
+	if n := buf.Len(); n > 0 && buf.Bytes()[n-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	for _, key := range keys {
+		if !updated[key] {
+			fmt.Fprintf(&buf, "%s=%s\n", key, quoteDotenv(values[key]))
+		}
+	}
+
+	return writeFileAtomic(path, buf.Bytes(), mode)
+}
+
+// FindFile searches for a file with the given name (e.g. .env) in the working directory and its parents,
+// like git finds .git, and returns the path of the first one found.
+// It allows tools run from the subdirectories of a project to pick up the project's file.
+// If the file is not found, the returned error matches [fs.ErrNotExist].
+func FindFile(name string) (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("env: getting working directory: %w", err)
+	}
+	for {
+		path := filepath.Join(dir, name)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("env: finding %s: %w", name, fs.ErrNotExist)
+		}
+		dir = parent
+	}
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and renames it to path.
+func writeFileAtomic(path string, data []byte, mode fs.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("env: creating temporary file: %w", err)
+	}
+	defer os.Remove(f.Name()) // no-op after a successful rename.
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("env: writing file: %w", err)
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return fmt.Errorf("env: writing file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("env: writing file: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("env: writing file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package env_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestUpdateFile(t *testing.T) {
+	t.Run("existing file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		err := os.WriteFile(path, []byte("# database\nexport DB_HOST=localhost\n  DB_PORT=5432 \n\nOTHER=1"), 0o640)
+		assert.NoErr[F](t, err)
+
+		err = env.UpdateFile(path, env.Map{"DB_PORT": "6543", "NEW": "x", "DB_HOST": "db"})
+		assert.NoErr[F](t, err)
+
+		data, err := os.ReadFile(path)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, string(data), "# database\nexport DB_HOST=db\n  DB_PORT=6543\n\nOTHER=1\nNEW=x\n")
+
+		fi, err := os.Stat(path)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, fi.Mode().Perm(), os.FileMode(0o640))
+	})
+
+	t.Run("new file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		err := env.UpdateFile(path, env.Map{"B": "2", "A": "1"})
+		assert.NoErr[F](t, err)
+
+		data, err := os.ReadFile(path)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, string(data), "A=1\nB=2\n")
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		err := os.WriteFile(path, []byte("M=\"line\nA=multi\"\nA=old\n"), 0o600)
+		assert.NoErr[F](t, err)
+
+		values := env.Map{"A": "a #b", "B": "'q'", "C": `"x`, "D": ` \$y `, "E": "plain"}
+		err = env.UpdateFile(path, values)
+		assert.NoErr[F](t, err)
+
+		data, err := os.ReadFile(path)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, string(data), "M=\"line\nA=multi\"\nA=\"a #b\"\nB=\"'q'\"\nC=\"\\\"x\"\nD=\" \\\\\\$y \"\nE=plain\n")
+
+		m, err := env.File(path)
+		assert.NoErr[F](t, err)
+		values["M"] = "line\nA=multi"
+		assert.Equal[E](t, m, values)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		err := env.UpdateFile(path, env.Map{"A": "1\n2"})
+		assert.Equal[E](t, err.Error(), "env: A: values must not contain newlines")
+	})
+}
+
+func TestFindFile(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	err := os.MkdirAll(sub, 0o700)
+	assert.NoErr[F](t, err)
+	err = os.WriteFile(filepath.Join(root, ".env"), nil, 0o600)
+	assert.NoErr[F](t, err)
+
+	wd, err := os.Getwd()
+	assert.NoErr[F](t, err)
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	err = os.Chdir(sub)
+	assert.NoErr[F](t, err)
+
+	path, err := env.FindFile(".env")
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, path, filepath.Join(root, ".env"))
+
+	_, err = env.FindFile(".env.missing")
+	assert.IsErr[E](t, err, fs.ErrNotExist)
+}
+
+func TestFile(t *testing.T) {
+	const data = `# database
+export DB_HOST=localhost # inline comment
+DB_PORT = 5432
+PASSWORD='pa$$ # word'
+GREETING="hello\t\"world\"\n"
+
+KEY="-----BEGIN KEY-----
+foo
+-----END KEY-----"
+URL=http://example.com/#anchor
+EMPTY=
+`
+	path := filepath.Join(t.TempDir(), ".env")
+	err := os.WriteFile(path, []byte(data), 0o600)
+	assert.NoErr[F](t, err)
+
+	m, err := env.File(path)
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, m, env.Map{
+		"DB_HOST":  "localhost",
+		"DB_PORT":  "5432",
+		"PASSWORD": "pa$$ # word",
+		"GREETING": "hello\t\"world\"\n",
+		"KEY":      "-----BEGIN KEY-----\nfoo\n-----END KEY-----",
+		"URL":      "http://example.com/#anchor",
+		"EMPTY":    "",
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := map[string]string{
+			"FOO=1\nBAR":            "line 2: invalid KEY=VALUE pair",
+			"FOO=\"1\n\n":           "line 1: unterminated quoted value",
+			"FOO='a\nb'\nBAR='1' 2": "line 3: unexpected characters after the quoted value",
+		}
+		for data, want := range tests {
+			err := os.WriteFile(path, []byte(data), 0o600)
+			assert.NoErr[F](t, err)
+			_, err = env.File(path)
+			assert.Equal[E](t, err.Error(), "env: "+path+": "+want)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		m, err := env.IgnoreMissing(env.File(filepath.Join(t.TempDir(), ".env.local")))
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, m, env.Map{})
+	})
+}
+
+func TestDecryptFile(t *testing.T) {
+	// a toy cipher standing in for age or SOPS.
+	xor := func(data []byte) ([]byte, error) {
+		out := make([]byte, len(data))
+		for i, b := range data {
+			out[i] = b ^ 0x2a
+		}
+		return out, nil
+	}
+
+	ciphertext, _ := xor([]byte("PASSWORD='s3cr3t'\n"))
+	path := filepath.Join(t.TempDir(), ".env.enc")
+	err := os.WriteFile(path, ciphertext, 0o600)
+	assert.NoErr[F](t, err)
+
+	m, err := env.DecryptFile(path, xor)
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, m, env.Map{"PASSWORD": "s3cr3t"})
+
+	errBadKey := errors.New("bad key")
+	_, err = env.DecryptFile(path, func([]byte) ([]byte, error) { return nil, errBadKey })
+	assert.IsErr[E](t, err, errBadKey)
+}
@@ -2,10 +2,23 @@
 package env
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"os/user"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
 // Options are the options for the [Load] and [Usage] functions.
@@ -13,21 +26,325 @@ type Options struct {
 	Source   Source // The source of environment variables. The default is [OS].
 	SliceSep string // The separator used to parse slice values. The default is space.
 	NameSep  string // The separator used to concatenate environment variable names from nested struct tags. The default is an empty string.
+	GroupSep string // The separator used to parse the groups of nested slice values, e.g. [][]string. The default is semicolon.
+	MapSep   string // The separator used to parse the pairs of map values. The default is comma.
+	KVSep    string // The separator used to parse the key and the value of map pairs. The default is equals sign.
+	Hooks    Hooks  // The optional callbacks to observe loading, e.g. to export metrics.
+
+	// IntBasePrefix makes [Load] parse integers with the base implied by their prefix:
+	// 0x for hex, 0o or 0 for octal, and 0b for binary; underscores are allowed as digit separators.
+	// See [strconv.ParseInt] with base 0 for details. Note that 0644 is parsed as octal in this mode.
+	IntBasePrefix bool
+
+	// LenientBool makes [Load] accept yes/no, y/n, on/off, enable/disable, and enabled/disabled (case-insensitive)
+	// as bool values, in addition to the values accepted by [strconv.ParseBool].
+	LenientBool bool
+
+	// LenientFloat makes [Load] accept floats with a comma as the decimal separator and with thousands separators,
+	// e.g. 1.234,5 or 1 234,5, as exported from spreadsheets and European tooling.
+	// If both a comma and a dot are present, the last one is the decimal separator.
+	LenientFloat bool
+
+	// Location is used to parse [time.Time] values without a time zone, e.g. 2024-01-01 09:00.
+	// The default is the location named by the TZ variable from the source, or UTC if it is not set.
+	Location *time.Location
+
+	// Suffix makes [Load] look up NAME_SUFFIX before NAME for each variable,
+	// where _ is [Options.NameSep] (or underscore, if empty), e.g. DB_HOST_EU before DB_HOST.
+	// It allows multi-region deployments to share a base environment and override selectively.
+	Suffix string
+
+	// SecretResolvers resolve the values of the form secret://NAME/PATH using the resolver registered for NAME,
+	// e.g. secret://vault/db#password. This decouples where a value lives from the variable names the struct uses.
+	// The resolved values are not expanded.
+	SecretResolvers map[string]SecretResolver
+
+	// Sources are the named sources that individual variables can be routed to with the `source` tag,
+	// e.g. `env:"DB_PASSWORD" source:"vault"`, so that only the secrets come from a secret manager.
+	// The values of the routed variables are expanded using [Options.ExpandSource] or [Options.Source].
+	Sources map[string]Source
+
+	// Parsers are the named parsers that individual variables can opt into with the `parser=NAME` tag option,
+	// e.g. `env:"TOKEN,parser=hexkey"`, for the fields of the same type that need different parsing.
+	// The returned value must be assignable to the field.
+	Parsers map[string]func(s string) (any, error)
+
+	// TrimQuotes makes [Load] remove a matching pair of single or double quotes around values,
+	// since some tools (e.g. docker-compose) pass them through literally.
+	TrimQuotes bool
+
+	// ExpandAll makes [Load] expand the values of all variables, as if they had the `expand` option.
+	// Use the `noexpand` option to opt out for a single variable, e.g. a password containing $.
+	ExpandAll bool
+
+	// ExpandSource is used to resolve the ${VAR} references when expanding values.
+	// The default is [Options.Source].
+	ExpandSource Source
+
+	// NoCache disables the cache of parsed struct types, see [ClearCache].
+	// The caller must pass the same value to both [Load] and [Usage].
+	NoCache bool
+
+	// OnlyFillUnset makes [Load] skip the struct fields that already hold a non-zero value.
+	// It allows layering: populate the struct from one mechanism first, then fill the gaps from the environment.
+	OnlyFillUnset bool
+
+	// DefaultMode defines the default value of a variable that has neither the `default` tag nor the `required` option.
+	// The default is [DefaultFromField].
+	DefaultMode DefaultMode
+
+	// MaxValueLen makes [Load] reject the values longer than the given number of bytes.
+	// It can be overridden per variable with the `maxlen:"N"` struct tag. Zero means no limit.
+	MaxValueLen int
+
+	// RejectControlChars makes [Load] reject the values containing control characters, e.g. newlines,
+	// as a defense-in-depth measure against injection into downstream commands and headers.
+	// The values are checked as found in the source, before the `unescape` option is applied.
+	RejectControlChars bool
+
+	// Concurrency makes [Load] look up up to the given number of variables concurrently before setting them,
+	// which speeds up the startup with slow remote sources. The errors are still reported in the order of the struct fields.
+	// Note that [Hooks.Lookup] is then called concurrently. It is ignored if [Options.Source] is a [BatchSource].
+	Concurrency int
+
+	// LoadTimeout bounds the total time [Load] spends looking up variables, so that a hanging remote source
+	// can't block the startup forever. If it is exceeded, Load fails with [ErrTimeout]. Zero means no limit.
+	// See also [Timeout] and [Retry] for bounding and retrying single lookups.
+	LoadTimeout time.Duration
+
+	// Provenance, if not nil, is filled by [Load] with the origin of each variable it sets, keyed by name.
+	// If [Options.Source] is a [MultiSource], the origin is the layer the value is taken from,
+	// which helps debugging precedence issues. Note that finding the layer requires additional lookups.
+	Provenance map[string]Origin
+
+	// Messages re-word the labels and column headers of the usage messages and [Handler], and the error messages, e.g. to localize them.
+	Messages Messages
+
+	// UsageFunc writes the usage message in [Usage], e.g. [ManUsage].
+	// The default is a table aligned with spaces. It takes precedence over the Usage method of the cfg's type.
+	UsageFunc func(vars []Var, w io.Writer, opts *Options)
+
+	batch    *batch          // the variables prefetched by [Load].
+	deadline time.Time       // set by [Load] from [Options.LoadTimeout].
+	ctx      context.Context // set by [LoadContext].
+}
+
+// batch holds the result of [BatchSource.LookupEnvs] or of the concurrent lookups, see [Options.Concurrency].
+type batch struct {
+	keys   map[string]struct{}
+	values map[string]string
+	errs   map[string]error
+	hooked bool // whether [Hooks.Lookup] has already been called for the keys.
+}
+
+// get returns the prefetched value of the key; prefetched is false if the key was not requested.
+func (b *batch) get(key string) (value string, ok, prefetched bool, err error) {
+	if b == nil {
+		return "", false, false, nil
+	}
+	if _, prefetched = b.keys[key]; !prefetched {
+		return "", false, false, nil
+	}
+	value, ok = b.values[key]
+	return value, ok, true, b.errs[key]
+}
+
+// DefaultMode defines the default value of a variable that has neither the `default` tag nor the `required` option.
+type DefaultMode int
+
+const (
+	// DefaultFromField uses the current value of the struct field as the default.
+	// If the variable is not set, [Load] leaves the field unchanged.
+	// This allows pre-populating the struct with defaults before calling [Load].
+	DefaultFromField DefaultMode = iota
+	// DefaultFromZero uses the zero value of the field's type as the default.
+	// If the variable is not set, [Load] resets the field to its zero value.
+	DefaultFromZero
+)
+
+// Hooks are the optional callbacks called by [Load] and [Usage].
+// A nil callback is ignored.
+// They can also be used to integrate with a tracing library, e.g. OpenTelemetry:
+//
+//	hooks := env.Hooks{
+//		LoadStart: func(ctx context.Context, typ reflect.Type, src env.Source) func(int, error) {
+//			_, span := tracer.Start(ctx, "env.Load")
+//			return func(vars int, err error) {
+//				span.SetAttributes(attribute.Int("env.vars", vars))
+//				span.End()
+//			}
+//		},
+//	}
+type Hooks struct {
+	// LoadStart is called at the beginning of [Load] with the type of the struct and the source used.
+	// ctx is the context given to [LoadContext], or [context.Background] for [Load].
+	// The returned function, if not nil, is called before Load returns with the number of variables and the result.
+	LoadStart func(ctx context.Context, typ reflect.Type, src Source) (done func(vars int, err error))
+	// Lookup is called after each environment variable lookup in [Options.Source].
+	Lookup func(name string, found bool, elapsed time.Duration, err error)
+	// SubscriberPanic is called with the recovered value when a [Watcher] subscriber panics.
+	SubscriberPanic func(recovered any)
+	// RefreshError is called when a [Holder] fails to refresh a variable.
+	RefreshError func(name string, err error)
+	// CacheHit is called when the variables of a struct type are taken from the cache instead of being parsed.
+	CacheHit func(typ reflect.Type)
+	// Warning is called by [Load] for the non-fatal findings that are worth logging, see [Warning].
+	Warning func(w Warning)
+}
+
+// Warning describes a non-fatal finding reported to [Hooks.Warning].
+type Warning struct {
+	Name string      // The name of the variable.
+	Kind WarningKind // The kind of the finding.
+}
+
+// String returns a human-readable description of the warning.
+func (w Warning) String() string {
+	switch w.Kind {
+	case WarnDeprecated:
+		return fmt.Sprintf("env: %s is deprecated", w.Name)
+	case WarnDefault:
+		return fmt.Sprintf("env: %s is not set, using the default value", w.Name)
+	case WarnEmpty:
+		return fmt.Sprintf("env: %s is set to an empty value", w.Name)
+	default:
+		return fmt.Sprintf("env: %s: unknown warning", w.Name)
+	}
+}
+
+// WarningKind is the kind of a [Warning].
+type WarningKind int
+
+const (
+	WarnDeprecated WarningKind = iota + 1 // A variable marked with the `deprecated` option is set.
+	WarnDefault                           // A variable with the `warndefault` option is not set, so the default value is used.
+	WarnEmpty                             // A variable is set to an empty value, which is likely a mistake.
+)
+
+// Messages are the user-facing strings that can be re-worded or localized by the application, see [Options.Messages].
+// The empty fields keep the default messages.
+type Messages struct {
+	Required string // The usage label of the required variables. The default is "required".
+	Default  string // The usage label preceding the default values. The default is "default".
+	Empty    string // The usage placeholder for the empty default strings. The default is "<empty>".
+	OneOf    string // The usage label preceding the allowed values, see the `oneof` option. The default is "one of".
+
+	// Headers are the column headers of [HTMLUsage], [CSVUsage], [TSVUsage], and the HTML page of [Handler].
+	// The defaults are "Name", "Type", etc. for HTML and "name", "type", etc. for CSV and TSV.
+	Headers Headers
+
+	// NotSet formats the message of [NotSetError] for the names of the variables.
+	// The default is "env: NAME is required but not set".
+	NotSet func(names []string) string
+}
+
+// Headers are the column headers of the tabular usage messages, see [Messages.Headers].
+// The empty fields keep the default headers.
+type Headers struct {
+	Name     string
+	Type     string
+	Value    string // Only used by [Handler].
+	Required string // Only used by [CSVUsage] and [TSVUsage].
+	Default  string
+	Expand   string // Only used by [CSVUsage] and [TSVUsage].
+	Secret   string // Only used by [CSVUsage] and [TSVUsage].
+	Usage    string
+}
+
+// or returns the headers with the empty fields set to the given defaults.
+func (h Headers) or(def Headers) Headers {
+	for _, p := range [][2]*string{
+		{&h.Name, &def.Name},
+		{&h.Type, &def.Type},
+		{&h.Value, &def.Value},
+		{&h.Required, &def.Required},
+		{&h.Default, &def.Default},
+		{&h.Expand, &def.Expand},
+		{&h.Secret, &def.Secret},
+		{&h.Usage, &def.Usage},
+	} {
+		if *p[0] == "" {
+			*p[0] = *p[1]
+		}
+	}
+	return h
+}
+
+// htmlHeaders and csvHeaders are the default headers, see [Messages.Headers].
+var (
+	htmlHeaders = Headers{Name: "Name", Type: "Type", Value: "Value", Default: "Default", Usage: "Usage"}
+	csvHeaders  = Headers{Name: "name", Type: "type", Required: "required", Default: "default", Expand: "expand", Secret: "secret", Usage: "usage"}
+)
+
+// messagesOf returns the messages from opts with the empty fields set to the defaults.
+func messagesOf(opts *Options) Messages {
+	var m Messages
+	if opts != nil {
+		m = opts.Messages
+	}
+	if m.Required == "" {
+		m.Required = "required"
+	}
+	if m.Default == "" {
+		m.Default = "default"
+	}
+	if m.Empty == "" {
+		m.Empty = "<empty>"
+	}
+	if m.OneOf == "" {
+		m.OneOf = "one of"
+	}
+	return m
 }
 
 // NotSetError is returned when required environment variables are not set.
 type NotSetError struct {
+	// Names are listed in the order of the struct fields, without duplicates.
+	// The variables of a map of structs are ordered by the map key.
 	Names []string
+
+	format func(names []string) string // see [Messages.NotSet].
 }
 
 // Error implements the error interface.
 func (e *NotSetError) Error() string {
+	if e.format != nil {
+		return e.format(e.Names)
+	}
 	if len(e.Names) == 1 {
 		return fmt.Sprintf("env: %s is required but not set", e.Names[0])
 	}
 	return fmt.Sprintf("env: %s are required but not set", strings.Join(e.Names, " "))
 }
 
+// ParseError is returned when the value of an environment variable can't be parsed into the struct field.
+type ParseError struct {
+	Name  string // The name of the variable.
+	Field string // The path of the struct field, e.g. DB.Port.
+	Err   error  // The underlying error.
+}
+
+// Error implements the error interface.
+// It returns the message of the underlying error, e.g. parsing int: ...
+func (e *ParseError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// ValidationError is returned when the value of an environment variable is parsed
+// but fails the checks declared in the struct tags, e.g. the `min` and `max` options or the `pattern` tag.
+type ValidationError struct {
+	Name  string // The name of the variable.
+	Field string // The path of the struct field, e.g. DB.Port.
+	Err   error  // The failed check.
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string { return "env: " + e.Name + ": " + e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error { return e.Err }
+
 // Load loads environment variables into the given struct.
 // cfg must be a non-nil struct pointer, otherwise Load panics.
 // If opts is nil, the default [Options] are used.
@@ -42,9 +359,16 @@ func (e *NotSetError) Error() string {
 //   - bool
 //   - string
 //   - [time.Duration]
+//   - [time.Time] (RFC 3339, or a naive date and time parsed in [Options.Location])
+//   - [os.FileMode] (octal, e.g. 0644)
+//   - [url.URL] (absolute, e.g. https://example.com)
+//   - [math/big.Int], [math/big.Float] (64-bit precision by default), and [math/big.Rat], e.g. 1/3
 //   - [encoding.TextUnmarshaler]
 //   - slices of any type above
+//   - slices of slices of any type above, parsed from groups separated by [Options.GroupSep], e.g. "a b;c"
+//   - maps of any types above, parsed from KEY1=VALUE1,KEY2=VALUE2, see [Options.MapSep] and [Options.KVSep]
 //   - nested structs of any depth
+//   - pointers to any type above, which are left nil if the variable is not set and has no default value
 //
 // See the [strconv].Parse* functions for the parsing rules.
 // User-defined types can be used by implementing the [encoding.TextUnmarshaler] interface.
@@ -53,12 +377,50 @@ func (e *NotSetError) Error() string {
 // allowing grouping of related environment variables.
 // If a nested struct has the optional `env:"PREFIX"` tag,
 // the environment variables declared by its fields are prefixed with PREFIX.
+// The prefix is followed by [Options.NameSep], which can be overridden for the nested struct
+// and its descendants with the sep option, e.g. `env:"DB,sep=__"`.
+//
+// A nested struct with the `when:"VAR=VALUE"` tag is only loaded if the environment variable VAR equals VALUE
+// (or its default value, if not set); otherwise, its fields are left unchanged and their `required` options are ignored.
+// Multiple values can be separated by |. VAR is not prefixed by nested struct tags.
+// This allows selecting one of the alternative configs, e.g. `when:"STORAGE_TYPE=s3"`.
+//
+// A field of type map[string]T, where T is a struct, with the `env:"PREFIX"` tag
+// is populated with one T per KEY discovered from the variables named PREFIX_KEY_FIELD,
+// where FIELD is a variable declared by T and _ is [Options.NameSep] (or underscore, if empty).
+// This requires the source to implement [EnvironSource].
+//
+// In map values, a backslash escapes the following character, so that the , and = separators
+// (and the backslash itself) can be used inside keys and values, e.g. DSN=host\=localhost\,port\=5432.
 //
 // Default values can be specified using the `default:"VALUE"` struct tag.
+// The value must be valid for the field's type, otherwise Load panics, even if the variable is set.
+// Without the tag, the DefaultEnvValue() string method of the field's type is used, if implemented,
+// so that custom types (e.g. ports) can carry their own defaults; otherwise, the default value depends on [Options.DefaultMode].
 //
 // The name of an environment variable can be followed by comma-separated options:
 //   - required: marks the environment variable as required
 //   - expand: expands the value of the environment variable using [os.Expand]
+//   - noexpand: disables expansion if [Options.ExpandAll] is set
+//   - unescape: decodes the \n, \r, \t, and \\ escape sequences, e.g. for PEM keys passed as a single line
+//   - expandhome: replaces a leading ~ or ~user with the home directory of the current or the named user
+//   - secret: marks the environment variable as sensitive, see [Fingerprint]
+//   - deprecated: reports a [Warning] if the environment variable is set
+//   - warndefault: reports a [Warning] if the environment variable is not set and the default value is used
+//   - json: unmarshals the value as JSON, which works for any field type, e.g. structs and slices of structs
+//   - size: parses a byte size into an integer field, e.g. 10MB or 1.5GiB, see [ParseSize]
+//   - parser=NAME: parses the value with the named parser, see [Options.Parsers]
+//   - min=N, max=N: reject the numbers outside the bounds with a [ValidationError], e.g. `env:"PORT,min=1,max=65535"`
+//   - oneof=A|B|C: rejects the values outside the allowed set with a [ValidationError], e.g. `env:"LOG_LEVEL,oneof=debug|info"`
+//
+// A field of type map[string]string with the `env:",remain,prefix=PREFIX"` tag collects the variables starting with PREFIX
+// that are not claimed by other fields, keyed by their full names, e.g. to pass them through to plugins.
+// This requires the source to implement [EnvironSource].
+//
+// The `pattern:"REGEXP"` struct tag makes Load reject the string values that don't match the regular expression
+// with a [ValidationError]; for slices of strings, each element must match.
+//
+// The `source` tag routes the variable to one of [Options.Sources], e.g. `env:"DB_PASSWORD" source:"vault"`.
 func Load(cfg any, opts *Options) error {
 	pv := reflect.ValueOf(cfg)
 	if !structPtr(pv) {
@@ -66,43 +428,650 @@ func Load(cfg any, opts *Options) error {
 	}
 
 	opts = setDefaultOptions(opts)
+	return load(pv.Elem(), opts, "")
+}
 
-	v := pv.Elem()
+// LoadContext is like [Load], but the lookups are bound to ctx, so that a hanging remote source can be canceled.
+// If [Options.Source] implements [ContextSource], ctx is passed to it. Once ctx is done, Load fails with ctx.Err() wrapped.
+func LoadContext(ctx context.Context, cfg any, opts *Options) error {
+	pv := reflect.ValueOf(cfg)
+	if !structPtr(pv) {
+		panic("env: cfg must be a non-nil struct pointer")
+	}
+
+	o := *setDefaultOptions(opts)
+	o.ctx = ctx
+	return load(pv.Elem(), &o, "")
+}
+
+// ValidateSpec checks the struct spec of cfg and returns all the problems found, joined with [errors.Join]:
+// duplicate names, invalid defaults, conflicting or unknown tag options, unsupported types, and unknown sources.
+// It is intended to be called from a unit test, so that mistakes are caught in CI rather than by [Load] at runtime:
+//
+//	func TestConfig(t *testing.T) {
+//		if err := env.ValidateSpec(new(Config), nil); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+//
+// cfg must be a non-nil struct pointer, otherwise ValidateSpec panics.
+func ValidateSpec(cfg any, opts *Options) error {
+	pv := reflect.ValueOf(cfg)
+	if !structPtr(pv) {
+		panic("env: cfg must be a non-nil struct pointer")
+	}
+
+	opts = setDefaultOptions(opts)
+
+	var errs []error
+	vars := parseSpec(pv.Elem(), opts, func(msg string) {
+		errs = append(errs, errors.New(msg))
+	})
+	for _, v := range vars {
+		if !v.dynamic && !v.json && v.parser == "" && !supportedType(v.Type) {
+			errs = append(errs, fmt.Errorf("env: unsupported type `%s` of %s", v.Type, v.Name))
+		}
+		if _, ok := opts.Sources[v.source]; v.source != "" && !ok {
+			errs = append(errs, fmt.Errorf("env: unknown source `%s` of %s", v.source, v.Name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// UnknownVars returns the names of the variables from [Options.Source] that start with the given prefix
+// but don't match any variable declared in cfg, sorted. It allows detecting typos and configuration drift,
+// e.g. APP_DB_HSOT instead of APP_DB_HOST. The source must implement [EnvironSource].
+// cfg must be a non-nil struct pointer, otherwise UnknownVars panics.
+func UnknownVars(cfg any, prefix string, opts *Options) ([]string, error) {
+	pv := reflect.ValueOf(cfg)
+	if !structPtr(pv) {
+		panic("env: cfg must be a non-nil struct pointer")
+	}
+
+	opts = setDefaultOptions(opts)
+
+	keys, ok := environKeys(opts.Source)
+	if !ok {
+		return nil, errors.New("env: the source does not implement EnvironSource")
+	}
+
+	return unclaimed(parseVars(pv.Elem(), opts), keys, "", prefix, "", opts), nil
+}
+
+// unclaimed returns the keys that start with the given prefix but don't match any of the variables, sorted.
+// The variables are prefixed with varPrefix. The maps of structs and the remainder fields (except the one named except)
+// claim all the keys starting with their prefixes.
+func unclaimed(vars []Var, keys []string, varPrefix, prefix, except string, opts *Options) []string {
+	known := make(map[string]struct{})
+	var prefixes []string
+	for _, v := range vars {
+		name := varPrefix + v.Name
+		switch {
+		case v.dynamic:
+			prefixes = append(prefixes, name+autoSep(opts))
+			continue
+		case v.remain:
+			// when collecting the keys for a remainder field, only the more specific ones claim their keys.
+			if p := strings.TrimSuffix(name, "*"); except == "" || (name != except && len(p) > len(prefix) && strings.HasPrefix(p, prefix)) {
+				prefixes = append(prefixes, p)
+			}
+			continue
+		}
+		known[name] = struct{}{}
+		if opts.Suffix != "" {
+			known[name+autoSep(opts)+opts.Suffix] = struct{}{}
+		}
+	}
+
+	var names []string
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if _, ok := known[key]; ok || hasAnyPrefix(key, prefixes) {
+			continue
+		}
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// hasOption reports whether the `env` tag value has the given option.
+func hasOption(tag, option string) bool {
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadAll loads a separate instance of T for each of the given prefixes.
+// The names of the environment variables declared in T are prefixed with the corresponding prefix as is,
+// e.g. LoadAll[DB]([]string{"PRIMARY_", "REPLICA_"}, nil) loads PRIMARY_HOST and REPLICA_HOST into two DB values.
+// T must be a struct type, otherwise LoadAll panics. See [Load] for details.
+func LoadAll[T any](prefixes []string, opts *Options) ([]T, error) {
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() != reflect.Struct {
+		panic("env: T must be a struct type")
+	}
+
+	opts = setDefaultOptions(opts)
+
+	cfgs := make([]T, len(prefixes))
+	for i, prefix := range prefixes {
+		if err := load(reflect.ValueOf(&cfgs[i]).Elem(), opts, prefix); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfgs, nil
+}
+
+// LoadValue is like [Load], but populates and returns a copy of proto instead of mutating it,
+// so that configs can be treated as immutable values and safely shared across goroutines.
+// The fields of proto are used as the defaults according to [Options.DefaultMode].
+// The copy is shallow, but proto is never modified, since the slices and maps are replaced rather than updated.
+// T must be a struct type, otherwise LoadValue panics.
+func LoadValue[T any](proto T, opts *Options) (T, error) {
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() != reflect.Struct {
+		panic("env: T must be a struct type")
+	}
+	cfg := proto
+	if err := Load(&cfg, opts); err != nil {
+		var zero T
+		return zero, err
+	}
+	return cfg, nil
+}
+
+// Provide returns a constructor that loads a new T using [Load], suitable for dependency injection frameworks,
+// e.g. fx.Provide(env.Provide[Config](nil)). Since wire requires named providers, assign it to a function there:
+//
+//	func ProvideConfig() (Config, error) { return env.Provide[Config](nil)() }
+//
+// T must be a struct type, otherwise Provide panics.
+func Provide[T any](opts *Options) func() (T, error) {
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() != reflect.Struct {
+		panic("env: T must be a struct type")
+	}
+	return func() (T, error) {
+		var cfg T
+		err := Load(&cfg, opts)
+		return cfg, err
+	}
+}
+
+func load(v reflect.Value, opts *Options, prefix string) (err error) {
 	vars := parseVars(v, opts)
-	cache[v.Type()] = vars
+	if !opts.NoCache {
+		cachePut(v.Type(), vars)
+	}
+
+	if opts.Hooks.LoadStart != nil {
+		ctx := opts.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if done := opts.Hooks.LoadStart(ctx, v.Type(), opts.Source); done != nil {
+			defer func() { done(len(vars), err) }()
+		}
+	}
+
+	if opts.LoadTimeout > 0 && opts.deadline.IsZero() {
+		o := *opts
+		o.deadline = time.Now().Add(opts.LoadTimeout)
+		opts = &o
+	}
+
+	if _, ok := opts.Source.(BatchSource); ok || opts.Concurrency > 1 {
+		if opts, err = prefetch(vars, opts, prefix); err != nil {
+			return err
+		}
+	}
 
 	var notset []string
 	for _, v := range vars {
-		value, ok := lookupEnv(opts.Source, v.Name, v.Expand)
+		if opts.OnlyFillUnset && !v.structField.IsZero() {
+			continue
+		}
+
+		if ok, err := checkConditions(v.conds, vars, opts, prefix); err != nil {
+			return err
+		} else if !ok {
+			continue // the variable belongs to a struct that is not selected.
+		}
+
+		name := prefix + v.Name
+		if v.dynamic {
+			names, err := loadMap(v, opts, name)
+			if err != nil {
+				return err
+			}
+			notset = append(notset, names...)
+			continue
+		}
+		if v.remain {
+			if err := loadRemain(v, vars, opts, prefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		vopts := routeOpts(opts, v)
+		value, ok, err := lookupEnv(vopts, name, v.Expand)
+		if err != nil {
+			return err
+		}
 		if !ok {
 			if v.Required {
-				notset = append(notset, v.Name)
+				notset = append(notset, name)
 				continue
 			}
 			if !v.hasDefaultTag {
+				if opts.DefaultMode == DefaultFromZero {
+					v.structField.Set(reflect.Zero(v.Type))
+				}
 				continue // nothing to set.
 			}
+			if v.warnDefault {
+				warn(opts, name, WarnDefault)
+			}
+			if opts.Provenance != nil {
+				opts.Provenance[name] = Origin{Default: true}
+			}
 			value = v.Default
+		} else {
+			if opts.Provenance != nil {
+				opts.Provenance[name] = originOf(vopts, name)
+			}
+			if v.deprecated {
+				warn(opts, name, WarnDeprecated)
+			}
+			if value == "" {
+				warn(opts, name, WarnEmpty)
+			}
 		}
 
-		var err error
-		if kindOf(v.structField, reflect.Slice) && !implements(v.structField, unmarshalerIface) {
-			err = setSlice(v.structField, strings.Split(value, opts.SliceSep))
-		} else {
-			err = setValue(v.structField, value)
+		if err := assign(v, name, value, ok, opts); err != nil {
+			return err
+		}
+	}
+
+	if len(notset) > 0 {
+		return &NotSetError{Names: dedupe(notset), format: opts.Messages.NotSet}
+	}
+
+	return nil
+}
+
+// assign sets the variable to the value, which is either found in the source or the default one.
+// The values found in the source are sanitized and unescaped first.
+func assign(v Var, name, value string, found bool, opts *Options) (err error) {
+	if found {
+		if err := sanitize(v, value, opts); err != nil {
+			return fmt.Errorf("env: %s: %w", name, err)
+		}
+		if v.unescape {
+			value = unescaper.Replace(value)
+		}
+	}
+
+	if v.expandHome {
+		if value, err = expandHome(value); err != nil {
+			return fmt.Errorf("env: %s: expanding home: %w", name, err)
+		}
+	}
+
+	if err := setVar(v, value, opts); err != nil {
+		return &ParseError{Name: name, Field: v.Field, Err: err}
+	}
+	if err := validate(v); err != nil {
+		return &ValidationError{Name: name, Field: v.Field, Err: err}
+	}
+	return nil
+}
+
+// prefetch looks up all the variables at once, using either [BatchSource] or [Options.Concurrency] goroutines,
+// and returns a copy of opts that serves the lookups from the result.
+func prefetch(vars []Var, opts *Options, prefix string) (*Options, error) {
+	b := &batch{keys: make(map[string]struct{})}
+	var keys []string
+	add := func(key string) {
+		if _, ok := b.keys[key]; !ok {
+			b.keys[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+	for _, v := range vars {
+		for _, cond := range v.conds {
+			add(prefix + cond.name)
+		}
+		if v.dynamic || v.remain || v.source != "" {
+			continue
 		}
+		if opts.Suffix != "" {
+			add(prefix + v.Name + autoSep(opts) + opts.Suffix)
+		}
+		add(prefix + v.Name)
+	}
+
+	if bs, ok := opts.Source.(BatchSource); ok {
+		values, err := lookupBatch(opts, bs, keys)
 		if err != nil {
+			return nil, err
+		}
+		b.values = values
+	} else {
+		b.values = make(map[string]string)
+		b.errs = make(map[string]error)
+		b.hooked = true
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, opts.Concurrency)
+		for _, key := range keys {
+			key := key
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				value, ok, err := lookupHooked(opts, key)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err != nil:
+					b.errs[key] = err
+				case ok:
+					b.values[key] = value
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	o := *opts
+	o.batch = b
+	return &o, nil
+}
+
+// lookupBatch looks up the keys in bs at once, failing with [ErrTimeout] if the [Options.LoadTimeout] deadline passes first,
+// or with the context's error if the ctx given to [LoadContext] is done first.
+func lookupBatch(opts *Options, bs BatchSource, keys []string) (map[string]string, error) {
+	if opts.deadline.IsZero() && opts.ctx == nil {
+		return bs.LookupEnvs(keys), nil
+	}
+	if opts.ctx != nil && opts.ctx.Err() != nil {
+		return nil, fmt.Errorf("env: batch lookup: %w", opts.ctx.Err())
+	}
+	if !opts.deadline.IsZero() && time.Until(opts.deadline) <= 0 {
+		return nil, fmt.Errorf("%w: batch lookup", ErrTimeout)
+	}
+
+	ch := make(chan map[string]string, 1)
+	go func() { ch <- bs.LookupEnvs(keys) }()
+
+	var timeout <-chan time.Time
+	if !opts.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(opts.deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var done <-chan struct{}
+	if opts.ctx != nil {
+		done = opts.ctx.Done()
+	}
+
+	select {
+	case values := <-ch:
+		return values, nil
+	case <-timeout:
+		return nil, fmt.Errorf("%w: batch lookup", ErrTimeout)
+	case <-done:
+		return nil, fmt.Errorf("env: batch lookup: %w", opts.ctx.Err())
+	}
+}
+
+// routeOpts returns a copy of opts that looks up the variable in the named source from its `source` tag, if any.
+// It panics if the source is not registered in [Options.Sources].
+func routeOpts(opts *Options, v Var) *Options {
+	if v.source == "" {
+		return opts
+	}
+	src, ok := opts.Sources[v.source]
+	if !ok {
+		panic(fmt.Sprintf("env: unknown source `%s`", v.source))
+	}
+	o := *opts
+	o.Source = src
+	o.batch = nil
+	if o.ExpandSource == nil {
+		o.ExpandSource = opts.Source
+	}
+	return &o
+}
+
+func warn(opts *Options, name string, kind WarningKind) {
+	if opts.Hooks.Warning != nil {
+		opts.Hooks.Warning(Warning{Name: name, Kind: kind})
+	}
+}
+
+// setVar parses the value and sets it to the struct field of the variable.
+func setVar(v Var, value string, opts *Options) error {
+	switch {
+	case v.parser != "":
+		return setParsed(v, value, opts)
+	case v.plainString:
+		v.structField.SetString(value)
+		return nil
+	case v.json:
+		ptr := reflect.New(v.structField.Type())
+		if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+			return fmt.Errorf("parsing json: %w", err)
+		}
+		v.structField.Set(ptr.Elem())
+		return nil
+	case kindOf(v.structField, reflect.Ptr):
+		elem := reflect.New(v.structField.Type().Elem())
+		ev := Var{structField: elem.Elem(), plainString: elem.Elem().Type() == stringType, size: v.size}
+		if err := setVar(ev, value, opts); err != nil {
 			return err
 		}
+		v.structField.Set(elem)
+		return nil
+	case v.size:
+		return setSize(v.structField, value)
+	case nestedSlice(v.structField.Type()):
+		return setNestedSlice(v.structField, strings.Split(value, opts.GroupSep), opts)
+	case kindOf(v.structField, reflect.Slice) && !implements(v.structField, unmarshalerIface):
+		return setSlice(v.structField, strings.Split(value, opts.SliceSep), opts)
+	case kindOf(v.structField, reflect.Map) && !implements(v.structField, unmarshalerIface):
+		return setMap(v.structField, value, opts)
+	default:
+		return setValue(v.structField, value, opts)
 	}
+}
 
-	if len(notset) > 0 {
-		return &NotSetError{Names: notset}
+// setParsed parses the value with the parser named by the `parser=NAME` option, see [Options.Parsers].
+func setParsed(v Var, value string, opts *Options) error {
+	parsed, err := opts.Parsers[v.parser](value)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", v.parser, err)
+	}
+	rv := reflect.ValueOf(parsed)
+	if !rv.IsValid() || !rv.Type().AssignableTo(v.structField.Type()) {
+		return fmt.Errorf("parsing %s: got %T, want %s", v.parser, parsed, v.structField.Type())
+	}
+	v.structField.Set(rv)
+	return nil
+}
+
+// condition is parsed from the `when:"VAR=VALUE1|VALUE2"` tag of a nested struct.
+type condition struct {
+	name   string
+	values []string
+}
+
+func parseCondition(s string) (*condition, bool) {
+	name, values, ok := strings.Cut(s, "=")
+	if !ok || name == "" || values == "" {
+		return nil, false
+	}
+	return &condition{name: name, values: strings.Split(values, "|")}, true
+}
+
+// checkConditions reports whether all conditions are met.
+// If the discriminator variable is not set, its default value is used.
+func checkConditions(conds []condition, vars []Var, opts *Options, prefix string) (bool, error) {
+	for _, cond := range conds {
+		value, ok, err := lookupEnv(opts, prefix+cond.name, false)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			for _, v := range vars {
+				if v.Name == cond.name && v.hasDefaultTag {
+					value = v.Default
+				}
+			}
+		}
+		matched := false
+		for _, want := range cond.values {
+			if value == want {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// loadMap loads a map of structs, whose keys are discovered from the environment variables named PREFIX<sep>KEY<sep>FIELD.
+// It returns the names of the required variables that are not set.
+func loadMap(v Var, opts *Options, prefix string) ([]string, error) {
+	keys, ok := environKeys(opts.Source)
+	if !ok {
+		return nil, fmt.Errorf("env: %s: the source does not implement EnvironSource", prefix)
+	}
+
+	sep := autoSep(opts)
+	prefix += sep
+	elemType := v.Type.Elem()
+
+	var names []string
+	for _, ev := range parseVars(reflect.New(elemType).Elem(), opts) {
+		names = append(names, sep+ev.Name)
+	}
+
+	found := make(map[string]struct{})
+	for _, key := range keys {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			if k, ok := strings.CutSuffix(rest, name); ok && k != "" {
+				found[k] = struct{}{}
+			}
+		}
+	}
+
+	if len(found) > 0 {
+		// copy the existing map (if any) so that the caller's one (e.g. the LoadValue proto) is not modified.
+		m := reflect.MakeMapWithSize(v.Type, v.structField.Len()+len(found))
+		for iter := v.structField.MapRange(); iter.Next(); {
+			m.SetMapIndex(iter.Key(), iter.Value())
+		}
+		v.structField.Set(m)
 	}
 
+	var notset []string
+	for _, k := range sortedKeys(found) {
+		elem := reflect.New(elemType).Elem()
+		err := load(elem, opts, prefix+k+sep)
+		var notSetErr *NotSetError
+		switch {
+		case errors.As(err, &notSetErr):
+			notset = append(notset, notSetErr.Names...)
+		case err != nil:
+			return nil, err
+		}
+		v.structField.SetMapIndex(reflect.ValueOf(k), elem)
+	}
+
+	return notset, nil
+}
+
+// loadRemain sets the remainder field to the variables under its prefix that are not claimed by other fields.
+func loadRemain(v Var, vars []Var, opts *Options, prefix string) error {
+	name := prefix + v.Name
+	keys, ok := environKeys(opts.Source)
+	if !ok {
+		return fmt.Errorf("env: %s: the source does not implement EnvironSource", name)
+	}
+
+	m := make(map[string]string)
+	for _, key := range unclaimed(vars, keys, prefix, strings.TrimSuffix(name, "*"), name, opts) {
+		value, ok, err := lookupHooked(opts, key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			m[key] = value
+		}
+	}
+	v.structField.Set(reflect.ValueOf(m))
 	return nil
 }
 
+// autoSep returns the separator for the names composed automatically,
+// i.e. the variables of a map of structs and the names with [Options.Suffix].
+func autoSep(opts *Options) string {
+	if opts.NameSep == "" {
+		return "_"
+	}
+	return opts.NameSep
+}
+
+// dedupe removes duplicates from names, keeping the first occurrence.
+func dedupe(names []string) []string {
+	seen := make(map[string]struct{}, len(names))
+	unique := names[:0]
+	for _, name := range names {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func setDefaultOptions(opts *Options) *Options {
 	if opts == nil {
 		opts = new(Options)
@@ -113,32 +1082,111 @@ func setDefaultOptions(opts *Options) *Options {
 	if opts.SliceSep == "" {
 		opts.SliceSep = " "
 	}
+	if opts.GroupSep == "" {
+		opts.GroupSep = ";"
+	}
+	if opts.MapSep == "" {
+		opts.MapSep = ","
+	}
+	if opts.KVSep == "" {
+		opts.KVSep = "="
+	}
 	return opts
 }
 
 func parseVars(v reflect.Value, opts *Options) []Var {
+	return parseSpec(v, opts, func(msg string) { panic(msg) })
+}
+
+// parseSpec parses the variables declared in the struct, reporting each problem with the spec to fail.
+// The fields with problems are skipped.
+func parseSpec(v reflect.Value, opts *Options, fail func(msg string)) []Var {
+	vars := parseFields(v, opts, "", opts.NameSep, fail)
+
+	paths := make(map[string]string, len(vars))
+	for _, v := range vars {
+		if v.dynamic || v.remain {
+			continue
+		}
+		if path, ok := paths[v.Name]; ok {
+			fail(fmt.Sprintf("env: duplicate name `%s` used by fields %s and %s", v.Name, path, v.Field))
+			continue
+		}
+		paths[v.Name] = v.Field
+	}
+
+	return vars
+}
+
+func parseFields(v reflect.Value, opts *Options, path, nameSep string, fail func(msg string)) []Var {
 	var vars []Var
 
+fields:
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
 		if !field.CanSet() {
 			continue
 		}
 
-		tags := v.Type().Field(i).Tag
+		sf := v.Type().Field(i)
+		tags := sf.Tag
+		fieldPath := path + sf.Name
 
-		if kindOf(field, reflect.Struct) && !implements(field, unmarshalerIface) {
+		// the `json` option makes any field a single variable, including structs and maps of structs.
+		jsonValue := hasOption(tags.Get("env"), "json")
+
+		if kindOf(field, reflect.Struct) && !implements(field, unmarshalerIface) && !typeOf(field, urlType) && !jsonValue {
 			var prefix string
+			sep := nameSep
 			if value, ok := tags.Lookup("env"); ok {
-				prefix = value + opts.NameSep
+				parts := strings.Split(value, ",")
+				for _, option := range parts[1:] {
+					s, ok := strings.CutPrefix(option, "sep=")
+					if !ok {
+						fail(fmt.Sprintf("env: invalid tag option `%s`", option))
+						continue fields
+					}
+					sep = s
+				}
+				prefix = parts[0] + sep
+			}
+			var cond *condition
+			if value, ok := tags.Lookup("when"); ok {
+				if cond, ok = parseCondition(value); !ok {
+					fail(fmt.Sprintf("env: invalid `when` tag `%s`", value))
+					continue
+				}
 			}
-			for _, v := range parseVars(field, opts) {
+			for _, v := range parseFields(field, opts, fieldPath+".", sep, fail) {
 				v.Name = prefix + v.Name
+				if cond != nil {
+					v.conds = append([]condition{*cond}, v.conds...)
+				}
 				vars = append(vars, v)
 			}
 			continue
 		}
 
+		if mapOfStructs(field) && !jsonValue {
+			name, ok := tags.Lookup("env")
+			if !ok {
+				continue
+			}
+			if name == "" {
+				fail("env: empty tag name is not allowed")
+				continue
+			}
+			vars = append(vars, Var{
+				Name:        name,
+				Type:        field.Type(),
+				Usage:       tags.Get("usage"),
+				structField: field,
+				Field:       fieldPath,
+				dynamic:     true,
+			})
+			continue
+		}
+
 		value, ok := tags.Lookup("env")
 		if !ok {
 			continue
@@ -146,28 +1194,226 @@ func parseVars(v reflect.Value, opts *Options) []Var {
 
 		parts := strings.Split(value, ",")
 		name, options := parts[0], parts[1:]
+
+		if hasOption(value, "remain") {
+			var remainPrefix string
+			for _, option := range options {
+				if p, ok := strings.CutPrefix(option, "prefix="); ok {
+					remainPrefix = p
+				} else if option != "remain" {
+					fail(fmt.Sprintf("env: invalid tag option `%s`", option))
+					continue fields
+				}
+			}
+			switch {
+			case name != "":
+				fail("env: `remain` can't be used with a name")
+				continue
+			case field.Type() != remainType:
+				fail(fmt.Sprintf("env: `remain` can't be used with type `%s`", field.Type()))
+				continue
+			}
+			vars = append(vars, Var{
+				Name:        remainPrefix + "*",
+				Type:        field.Type(),
+				Usage:       tags.Get("usage"),
+				Default:     formatValue(field),
+				structField: field,
+				Field:       fieldPath,
+				remain:      true,
+			})
+			continue
+		}
+
 		if name == "" {
-			panic("env: empty tag name is not allowed")
+			fail("env: empty tag name is not allowed")
+			continue
 		}
 
-		var required, expand bool
+		var required, expand, noexpand, secret, unescape, expandHome, deprecated, warnDefault, size bool
+		var parser, minStr, maxStr string
+		var minSet, maxSet bool
+		var oneOf []string
 		for _, option := range options {
+			if s, ok := strings.CutPrefix(option, "oneof="); ok {
+				oneOf = strings.Split(s, "|")
+				continue
+			}
+			if name, ok := strings.CutPrefix(option, "parser="); ok {
+				parser = name
+				continue
+			}
+			if s, ok := strings.CutPrefix(option, "min="); ok {
+				minStr, minSet = s, true
+				continue
+			}
+			if s, ok := strings.CutPrefix(option, "max="); ok {
+				maxStr, maxSet = s, true
+				continue
+			}
 			switch option {
 			case "required":
 				required = true
 			case "expand":
 				expand = true
+			case "noexpand":
+				noexpand = true
+			case "secret":
+				secret = true
+			case "unescape":
+				unescape = true
+			case "expandhome":
+				expandHome = true
+			case "deprecated":
+				deprecated = true
+			case "warndefault":
+				warnDefault = true
+			case "json":
+				// already parsed, see jsonValue.
+			case "size":
+				size = true
 			default:
-				panic(fmt.Sprintf("env: invalid tag option `%s`", option))
+				fail(fmt.Sprintf("env: invalid tag option `%s`", option))
+				continue fields
 			}
 		}
 
+		if expand && noexpand {
+			fail("env: `expand` and `noexpand` can't be used simultaneously")
+			continue
+		}
+		if _, ok := opts.Parsers[parser]; parser != "" && !ok {
+			fail(fmt.Sprintf("env: unknown parser `%s`", parser))
+			continue
+		}
+		if size && !integer(field.Type()) {
+			fail(fmt.Sprintf("env: `size` can't be used with type `%s`", field.Type()))
+			continue
+		}
+		if (minSet || maxSet) && !numeric(field.Type()) {
+			fail(fmt.Sprintf("env: `min` and `max` can't be used with type `%s`", field.Type()))
+			continue
+		}
+		var oneOfValues []reflect.Value
+		if oneOf != nil {
+			if !scalar(field.Type()) {
+				fail(fmt.Sprintf("env: `oneof` can't be used with type `%s`", field.Type()))
+				continue
+			}
+			for _, s := range oneOf {
+				value, err := parseOption(field.Type(), s, size, opts)
+				if err != nil {
+					fail(fmt.Sprintf("env: invalid oneof value `%s` of %s: %v", s, name, err))
+					continue fields
+				}
+				oneOfValues = append(oneOfValues, value)
+			}
+		}
+		var minValue, maxValue reflect.Value
+		if minSet {
+			var err error
+			if minValue, err = parseOption(field.Type(), minStr, size, opts); err != nil {
+				fail(fmt.Sprintf("env: invalid min `%s` of %s: %v", minStr, name, err))
+				continue
+			}
+		}
+		if maxSet {
+			var err error
+			if maxValue, err = parseOption(field.Type(), maxStr, size, opts); err != nil {
+				fail(fmt.Sprintf("env: invalid max `%s` of %s: %v", maxStr, name, err))
+				continue
+			}
+		}
+		expand = expand || (opts.ExpandAll && !noexpand)
+
+		var maxLen int
+		if value, ok := tags.Lookup("maxlen"); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				fail(fmt.Sprintf("env: invalid maxlen `%s`", value))
+				continue
+			}
+			maxLen = n
+		}
+
+		var pattern *regexp.Regexp
+		if value, ok := tags.Lookup("pattern"); ok {
+			if !stringly(field.Type()) {
+				fail(fmt.Sprintf("env: `pattern` can't be used with type `%s`", field.Type()))
+				continue
+			}
+			re, err := regexp.Compile(value)
+			if err != nil {
+				fail(fmt.Sprintf("env: invalid pattern `%s`: %v", value, err))
+				continue
+			}
+			pattern = re
+		}
+
+		source, ok := tags.Lookup("source")
+		if ok && source == "" {
+			fail("env: empty `source` tag is not allowed")
+			continue
+		}
+
+		var refresh time.Duration
+		if value, ok := tags.Lookup("refresh"); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				fail(fmt.Sprintf("env: invalid refresh interval `%s`", value))
+				continue fields
+			}
+			refresh = d
+		}
+
+		// plain strings need neither parsing nor formatting, so they take the fast path.
+		plainString := field.Type() == stringType && !jsonValue && parser == ""
+
 		defValue, defSet := tags.Lookup("default")
+		if !defSet && !required {
+			defValue, defSet = typeDefault(field)
+		}
 		switch {
 		case defSet && required:
-			panic("env: `required` and `default` can't be used simultaneously")
+			fail("env: `required` and `default` can't be used simultaneously")
+			continue
+		case !defSet && !required && plainString:
+			if opts.DefaultMode == DefaultFromField {
+				defValue = field.String()
+			}
+		case !defSet && !required && field.Kind() == reflect.Ptr:
+			if opts.DefaultMode == DefaultFromField && !field.IsNil() {
+				defValue = formatValue(field.Elem())
+			}
+		case !defSet && !required && opts.DefaultMode == DefaultFromZero:
+			defValue = formatValue(reflect.New(field.Type()).Elem())
 		case !defSet && !required:
-			defValue = fmt.Sprintf("%v", field.Interface())
+			defValue = formatValue(field)
+		}
+
+		// catch broken defaults early rather than when the variable is first omitted.
+		if defSet && (jsonValue || parser != "" || supportedType(field.Type())) {
+			scratch := Var{
+				Secret:      secret,
+				OneOf:       oneOf,
+				structField: reflect.New(field.Type()).Elem(),
+				plainString: plainString,
+				json:        jsonValue,
+				size:        size,
+				parser:      parser,
+				min:         minValue,
+				max:         maxValue,
+				pattern:     pattern,
+				oneOf:       oneOfValues,
+			}
+			err := setVar(scratch, defValue, opts)
+			if err == nil {
+				err = validate(scratch)
+			}
+			if err != nil {
+				fail(fmt.Sprintf("env: invalid default value `%s` of %s: %v", defValue, name, err))
+				continue
+			}
 		}
 
 		vars = append(vars, Var{
@@ -177,25 +1423,197 @@ func parseVars(v reflect.Value, opts *Options) []Var {
 			Default:       defValue,
 			Required:      required,
 			Expand:        expand,
+			Secret:        secret,
 			structField:   field,
+			Field:         fieldPath,
 			hasDefaultTag: defSet,
+			plainString:   plainString,
+			unescape:      unescape,
+			expandHome:    expandHome,
+			maxLen:        maxLen,
+			deprecated:    deprecated,
+			warnDefault:   warnDefault,
+			json:          jsonValue,
+			size:          size,
+			parser:        parser,
+			min:           minValue,
+			max:           maxValue,
+			pattern:       pattern,
+			OneOf:         oneOf,
+			oneOf:         oneOfValues,
+			source:        source,
+			refresh:       refresh,
 		})
 	}
 
 	return vars
 }
 
-func lookupEnv(src Source, key string, expand bool) (string, bool) {
-	value, ok := src.LookupEnv(key)
-	if !ok {
-		return "", false
+// lookupHooked looks up the key in [Options.Source], calling [Hooks.Lookup] if set.
+func lookupHooked(opts *Options, key string) (string, bool, error) {
+	start := time.Now()
+	value, ok, prefetched, err := opts.batch.get(key)
+	if prefetched && opts.batch.hooked {
+		return value, ok, err
+	}
+	switch {
+	case prefetched:
+	case !opts.deadline.IsZero() && time.Until(opts.deadline) <= 0:
+		err = fmt.Errorf("%w: %s", ErrTimeout, key)
+	case opts.ctx != nil:
+		ctx := opts.ctx
+		if !opts.deadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, opts.deadline)
+			defer cancel()
+		}
+		value, ok, err = lookupContext(ctx, opts.Source, key)
+		if err != nil && opts.ctx.Err() == nil && ctx.Err() != nil {
+			err = fmt.Errorf("%w: %s", ErrTimeout, key)
+		}
+	case opts.deadline.IsZero():
+		value, ok, err = lookup(opts.Source, key)
+	default:
+		ts := timeoutSource{src: opts.Source, timeout: time.Until(opts.deadline), policy: FailClosed}
+		value, ok, err = ts.LookupEnvErr(key)
+	}
+	if opts.Hooks.Lookup != nil {
+		opts.Hooks.Lookup(key, ok, time.Since(start), err)
+	}
+	return value, ok, err
+}
+
+func lookupEnv(opts *Options, key string, expand bool) (string, bool, error) {
+	var value string
+	var ok bool
+	var err error
+	if opts.Suffix != "" {
+		value, ok, err = lookupHooked(opts, key+autoSep(opts)+opts.Suffix)
+	}
+	if err == nil && !ok {
+		value, ok, err = lookupHooked(opts, key)
+	}
+	if err != nil || !ok {
+		return "", false, err
+	}
+	if opts.TrimQuotes {
+		value = trimQuotes(value)
+	}
+	if len(opts.SecretResolvers) > 0 && strings.HasPrefix(value, "secret://") {
+		value, err = resolveSecret(opts, key, value)
+		return value, err == nil, err
 	}
 	if !expand {
-		return value, true
+		return value, true, nil
+	}
+	src := opts.Source
+	if opts.ExpandSource != nil {
+		src = opts.ExpandSource
 	}
 	mapping := func(key string) string {
-		v, _ := src.LookupEnv(key)
+		v, _, e := lookup(src, key)
+		if e != nil && err == nil {
+			err = e
+		}
 		return v
 	}
-	return os.Expand(value, mapping), true
+	value = os.Expand(value, mapping)
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// typeDefault returns the default value provided by the field's type, see [Load].
+func typeDefault(v reflect.Value) (string, bool) {
+	if !implements(v, defaultValuerIface) {
+		return "", false
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		v = reflect.New(v.Type().Elem()) // don't call the method on a nil pointer.
+	}
+	if !v.Type().Implements(defaultValuerIface) {
+		v = v.Addr()
+	}
+	return v.Interface().(interface{ DefaultEnvValue() string }).DefaultEnvValue(), true
+}
+
+// sanitize checks the value against [Options.MaxValueLen] (or the `maxlen` tag) and [Options.RejectControlChars].
+func sanitize(v Var, value string, opts *Options) error {
+	maxLen := opts.MaxValueLen
+	if v.maxLen > 0 {
+		maxLen = v.maxLen
+	}
+	if maxLen > 0 && len(value) > maxLen {
+		return fmt.Errorf("value exceeds the maximum length of %d bytes", maxLen)
+	}
+	if opts.RejectControlChars && strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return errors.New("value contains control characters")
+	}
+	return nil
+}
+
+// expandHome replaces a leading ~ or ~user with the home directory for the `expandhome` option.
+func expandHome(s string) (string, error) {
+	if !strings.HasPrefix(s, "~") {
+		return s, nil
+	}
+
+	name, rest := s[1:], ""
+	if i := strings.IndexAny(name, `/`+string(filepath.Separator)); i >= 0 {
+		name, rest = name[:i], name[i:]
+	}
+
+	if name == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return home + rest, nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return u.HomeDir + rest, nil
+}
+
+// unescaper decodes the escape sequences for the `unescape` option.
+var unescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r", `\t`, "\t")
+
+// SecretResolver resolves the secret:// references, see [Options.SecretResolvers].
+type SecretResolver interface {
+	// ResolveSecret returns the value of the secret referenced by the given URL.
+	ResolveSecret(ref *url.URL) (string, error)
+}
+
+// SecretResolverFunc is an adapter to use a function as a [SecretResolver].
+type SecretResolverFunc func(ref *url.URL) (string, error)
+
+// ResolveSecret implements the [SecretResolver] interface.
+func (fn SecretResolverFunc) ResolveSecret(ref *url.URL) (string, error) { return fn(ref) }
+
+func resolveSecret(opts *Options, key, value string) (string, error) {
+	ref, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("env: %s: parsing secret reference: %w", key, err)
+	}
+	r, ok := opts.SecretResolvers[ref.Host]
+	if !ok {
+		return "", fmt.Errorf("env: %s: no secret resolver for %q", key, ref.Host)
+	}
+	secret, err := r.ResolveSecret(ref)
+	if err != nil {
+		return "", fmt.Errorf("env: %s: resolving secret: %w", key, err)
+	}
+	return secret, nil
+}
+
+// trimQuotes removes a matching pair of single or double quotes around s.
+func trimQuotes(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
 }
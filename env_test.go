@@ -1,10 +1,21 @@
 package env_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"io"
+	"math/big"
 	"net"
+	"net/url"
+	"os"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -33,6 +44,21 @@ func TestLoad(t *testing.T) {
 
 				usage := func() { env.Usage(cfg, io.Discard, nil) }
 				assert.Panics[E](t, usage, panicMsg)
+
+				fingerprint := func() { env.Fingerprint(cfg, nil) }
+				assert.Panics[E](t, fingerprint, panicMsg)
+
+				visit := func() { env.VisitVars(cfg, nil, nil) }
+				assert.Panics[E](t, visit, panicMsg)
+
+				handler := func() { env.Handler(cfg, nil) }
+				assert.Panics[E](t, handler, panicMsg)
+
+				resolve := func() { env.Resolve(cfg, "", nil) }
+				assert.Panics[E](t, resolve, panicMsg)
+
+				watch := func() { env.Watch(cfg, 0, nil) }
+				assert.Panics[E](t, watch, panicMsg)
 			})
 		}
 	})
@@ -53,6 +79,14 @@ func TestLoad(t *testing.T) {
 		assert.Panics[E](t, load, "env: invalid tag option `?`")
 	})
 
+	t.Run("invalid refresh interval", func(t *testing.T) {
+		var cfg struct {
+			Foo int `env:"FOO" refresh:"-1s"`
+		}
+		load := func() { _ = env.Load(&cfg, nil) }
+		assert.Panics[E](t, load, "env: invalid refresh interval `-1s`")
+	})
+
 	t.Run("required with default", func(t *testing.T) {
 		var cfg struct {
 			Foo int `env:"FOO,required" default:"1"`
@@ -61,6 +95,198 @@ func TestLoad(t *testing.T) {
 		assert.Panics[E](t, load, "env: `required` and `default` can't be used simultaneously")
 	})
 
+	t.Run("invalid default", func(t *testing.T) {
+		var cfg struct {
+			Foo int `env:"FOO" default:"one"`
+		}
+		load := func() { _ = env.Load(&cfg, &env.Options{Source: env.Map{"FOO": "1"}}) }
+		assert.Panics[E](t, load, "env: invalid default value `one` of FOO: parsing int: strconv.ParseInt: parsing \"one\": invalid syntax")
+	})
+
+	t.Run("named sources", func(t *testing.T) {
+		var cfg struct {
+			User     string `env:"DB_USER"`
+			Password string `env:"DB_PASSWORD,required" source:"vault"`
+		}
+		opts := &env.Options{
+			Source:  env.Map{"DB_USER": "admin", "DB_PASSWORD": "env"},
+			Sources: map[string]env.Source{"vault": env.Map{"DB_PASSWORD": "vault"}},
+		}
+		err := env.Load(&cfg, opts)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.User, "admin")
+		assert.Equal[E](t, cfg.Password, "vault")
+
+		load := func() { _ = env.Load(&cfg, nil) }
+		assert.Panics[E](t, load, "env: unknown source `vault`")
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		var cfg struct {
+			Foo int `env:"A_FOO"`
+			A   struct {
+				Foo int `env:"FOO"`
+			} `env:"A_"`
+		}
+		load := func() { _ = env.Load(&cfg, nil) }
+		assert.Panics[E](t, load, "env: duplicate name `A_FOO` used by fields Foo and A.Foo")
+	})
+
+	t.Run("not set order", func(t *testing.T) {
+		var cfg struct {
+			C int `env:"C,required"`
+			A struct {
+				B int `env:"B,required"`
+			}
+			DBs map[string]struct {
+				Host string `env:"HOST,required"`
+				Port int    `env:"PORT"`
+			} `env:"DB"`
+			Z int `env:"Z,required"`
+		}
+		m := env.Map{"DB_Y_PORT": "1", "DB_X_PORT": "2"}
+		for i := 0; i < 10; i++ {
+			err := env.Load(&cfg, &env.Options{Source: m, NameSep: "_"})
+			var notSetErr *env.NotSetError
+			assert.AsErr[F](t, err, &notSetErr)
+			assert.Equal[E](t, notSetErr.Names, []string{"C", "B", "DB_X_HOST", "DB_Y_HOST", "Z"})
+		}
+	})
+
+	t.Run("suffix", func(t *testing.T) {
+		m := env.Map{"DB_HOST": "db", "DB_HOST_EU": "db.eu", "DB_PORT": "5432"}
+
+		var cfg struct {
+			Host string `env:"DB_HOST"`
+			Port int    `env:"DB_PORT"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, Suffix: "EU"})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Host, "db.eu")
+		assert.Equal[E](t, cfg.Port, 5432)
+	})
+
+	t.Run("secret resolvers", func(t *testing.T) {
+		vault := env.SecretResolverFunc(func(ref *url.URL) (string, error) {
+			if ref.Path == "/db" && ref.Fragment == "password" {
+				return "qwerty", nil
+			}
+			return "", errors.New("not found")
+		})
+		opts := func(m env.Map) *env.Options {
+			return &env.Options{Source: m, SecretResolvers: map[string]env.SecretResolver{"vault": vault}}
+		}
+
+		var cfg struct {
+			Password string `env:"DB_PASSWORD"`
+		}
+		err := env.Load(&cfg, opts(env.Map{"DB_PASSWORD": "secret://vault/db#password"}))
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Password, "qwerty")
+
+		err = env.Load(&cfg, opts(env.Map{"DB_PASSWORD": "secret://vault/db#user"}))
+		assert.Equal[E](t, err.Error(), "env: DB_PASSWORD: resolving secret: not found")
+
+		err = env.Load(&cfg, opts(env.Map{"DB_PASSWORD": "secret://ssm/db"}))
+		assert.Equal[E](t, err.Error(), `env: DB_PASSWORD: no secret resolver for "ssm"`)
+	})
+
+	t.Run("trim quotes", func(t *testing.T) {
+		m := env.Map{"PORT": `"8080"`, "HOST": "'localhost'", "NAME": `"foo'`}
+
+		var cfg struct {
+			Port int    `env:"PORT"`
+			Host string `env:"HOST"`
+			Name string `env:"NAME"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, TrimQuotes: true})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Port, 8080)
+		assert.Equal[E](t, cfg.Host, "localhost")
+		assert.Equal[E](t, cfg.Name, `"foo'`)
+	})
+
+	t.Run("unescape", func(t *testing.T) {
+		m := env.Map{"KEY": `-----BEGIN KEY-----\nfoo\tbar\\n\n-----END KEY-----`}
+
+		var cfg struct {
+			Key string `env:"KEY,unescape"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Key, "-----BEGIN KEY-----\nfoo\tbar\\n\n-----END KEY-----")
+	})
+
+	t.Run("sanitization", func(t *testing.T) {
+		m := env.Map{"NAME": "foo", "HEADER": "foo\r\nX-Injected: 1", "TOKEN": "0123456789"}
+
+		var cfg struct {
+			Name   string `env:"NAME"`
+			Header string `env:"HEADER"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, RejectControlChars: true})
+		assert.Equal[E](t, err.Error(), "env: HEADER: value contains control characters")
+
+		var cfg2 struct {
+			Name  string `env:"NAME"`
+			Token string `env:"TOKEN" maxlen:"16"`
+		}
+		err = env.Load(&cfg2, &env.Options{Source: m, MaxValueLen: 8})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg2.Token, "0123456789")
+
+		err = env.Load(&cfg2, &env.Options{Source: m, MaxValueLen: 2})
+		assert.Equal[E](t, err.Error(), "env: NAME: value exceeds the maximum length of 2 bytes")
+	})
+
+	t.Run("expandhome", func(t *testing.T) {
+		t.Setenv("HOME", "/home/gopher")
+		m := env.Map{"DIR": "~/data", "PLAIN": "/tmp/~"}
+
+		var cfg struct {
+			Dir    string `env:"DIR,expandhome"`
+			Plain  string `env:"PLAIN,expandhome"`
+			Config string `env:"CONFIG,expandhome" default:"~"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Dir, "/home/gopher/data")
+		assert.Equal[E](t, cfg.Plain, "/tmp/~")
+		assert.Equal[E](t, cfg.Config, "/home/gopher")
+	})
+
+	t.Run("expand with noexpand", func(t *testing.T) {
+		var cfg struct {
+			Foo int `env:"FOO,expand,noexpand"`
+		}
+		load := func() { _ = env.Load(&cfg, nil) }
+		assert.Panics[E](t, load, "env: `expand` and `noexpand` can't be used simultaneously")
+	})
+
+	t.Run("expand all", func(t *testing.T) {
+		m := env.Map{"PORT": "8080", "ADDR": "localhost:${PORT}", "PASSWORD": "pa$$word"}
+
+		var cfg struct {
+			Addr     string `env:"ADDR"`
+			Password string `env:"PASSWORD,noexpand"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, ExpandAll: true})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Addr, "localhost:8080")
+		assert.Equal[E](t, cfg.Password, "pa$$word")
+	})
+
+	t.Run("expand source", func(t *testing.T) {
+		m := env.Map{"ADDR": "localhost:${PORT}", "PORT": "80"}
+
+		var cfg struct {
+			Addr string `env:"ADDR,expand"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, ExpandSource: env.Map{"PORT": "8080"}})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Addr, "localhost:8080")
+	})
+
 	t.Run("nested struct w/ and w/o tag", func(t *testing.T) {
 		m := env.Map{"A_FOO": "1", "BAR": "2"}
 
@@ -78,6 +304,212 @@ func TestLoad(t *testing.T) {
 		assert.Equal[E](t, cfg.B.Bar, 2)
 	})
 
+	t.Run("hooks", func(t *testing.T) {
+		m := env.Map{"FOO": "1"}
+
+		var lookups []string
+		var cacheHits int
+		hooks := env.Hooks{
+			Lookup: func(name string, found bool, _ time.Duration, err error) {
+				lookups = append(lookups, name+"="+strconv.FormatBool(found))
+				assert.NoErr[E](t, err)
+			},
+			CacheHit: func(reflect.Type) { cacheHits++ },
+		}
+
+		var loaded int
+		hooks.LoadStart = func(ctx context.Context, _ reflect.Type, _ env.Source) func(int, error) {
+			assert.Equal[E](t, ctx, context.Background())
+			return func(vars int, err error) {
+				loaded = vars
+				assert.NoErr[E](t, err)
+			}
+		}
+
+		var cfg struct {
+			Foo int `env:"FOO"`
+			Bar int `env:"BAR"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, Hooks: hooks})
+		assert.NoErr[F](t, err)
+		env.Usage(&cfg, io.Discard, &env.Options{Hooks: hooks})
+		assert.Equal[E](t, lookups, []string{"FOO=true", "BAR=false"})
+		assert.Equal[E](t, cacheHits, 1)
+		assert.Equal[E](t, loaded, 2)
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		m := env.Map{"OLD_HOST": "localhost", "NAME": ""}
+
+		var warnings []string
+		hooks := env.Hooks{
+			Warning: func(w env.Warning) { warnings = append(warnings, w.String()) },
+		}
+
+		var cfg struct {
+			Host string `env:"OLD_HOST,deprecated"`
+			Name string `env:"NAME"`
+			Port int    `env:"PORT,warndefault" default:"8080"`
+			Mode string `env:"MODE" default:"dev"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, Hooks: hooks})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, warnings, []string{
+			"env: OLD_HOST is deprecated",
+			"env: NAME is set to an empty value",
+			"env: PORT is not set, using the default value",
+		})
+	})
+
+	t.Run("default mode", func(t *testing.T) {
+		type config struct {
+			Foo int `env:"FOO"`
+		}
+
+		cfg := config{Foo: 1}
+		err := env.Load(&cfg, &env.Options{Source: env.Map{}})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Foo, 1)
+
+		cfg = config{Foo: 1}
+		err = env.Load(&cfg, &env.Options{Source: env.Map{}, DefaultMode: env.DefaultFromZero})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Foo, 0)
+	})
+
+	t.Run("type defaults", func(t *testing.T) {
+		var cfg struct {
+			Port    port `env:"PORT"`
+			Admin   port `env:"ADMIN_PORT" default:"9090"`
+			Mode    mode `env:"MODE"`
+			Debug   port `env:"DEBUG_PORT"`
+			Metrics port `env:"METRICS_PORT,required"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: env.Map{"DEBUG_PORT": "6060", "METRICS_PORT": "9100"}})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Port, port(8080))
+		assert.Equal[E](t, cfg.Admin, port(9090))
+		assert.Equal[E](t, cfg.Mode, mode("release"))
+		assert.Equal[E](t, cfg.Debug, port(6060))
+		assert.Equal[E](t, cfg.Metrics, port(9100))
+	})
+
+	t.Run("type defaults of pointers", func(t *testing.T) {
+		var cfg struct {
+			Port *port `env:"PORT"`
+			Mode *mode `env:"MODE"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: env.Map{}})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, *cfg.Port, port(8080))
+		assert.Equal[E](t, *cfg.Mode, mode("release"))
+	})
+
+	t.Run("only fill unset", func(t *testing.T) {
+		m := env.Map{"FOO": "1", "BAR": "2"}
+
+		var cfg struct {
+			Foo int `env:"FOO,required"`
+			Bar int `env:"BAR"`
+		}
+		cfg.Foo = 10
+		err := env.Load(&cfg, &env.Options{Source: m, OnlyFillUnset: true})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Foo, 10)
+		assert.Equal[E](t, cfg.Bar, 2)
+	})
+
+	t.Run("map of structs", func(t *testing.T) {
+		m := env.Map{
+			"DB_SOURCE_HOST": "db1",
+			"DB_DEST_HOST":   "db2",
+			"DB_DEST_PORT":   "5433",
+			"DB_BAD_PORT":    "5434",
+		}
+
+		type DB struct {
+			Host string `env:"HOST,required"`
+			Port int    `env:"PORT" default:"5432"`
+		}
+		var cfg struct {
+			DBs map[string]DB `env:"DB"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.Equal[E](t, err.Error(), "env: DB_BAD_HOST is required but not set")
+		assert.Equal[E](t, cfg.DBs, map[string]DB{
+			"SOURCE": {Host: "db1", Port: 5432},
+			"DEST":   {Host: "db2", Port: 5433},
+			"BAD":    {Port: 5434},
+		})
+
+		var buf bytes.Buffer
+		env.Usage(&cfg, &buf, &env.Options{Source: m})
+		assert.Equal[E](t, buf.String(), "  DB_<KEY>_HOST  string  required\n  DB_<KEY>_PORT  int     default 5432\n")
+	})
+
+	t.Run("map of structs w/o EnvironSource", func(t *testing.T) {
+		var cfg struct {
+			DBs map[string]struct {
+				Host string `env:"HOST"`
+			} `env:"DB"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: env.Allow(env.Map{})})
+		assert.Equal[E](t, err.Error(), "env: DB: the source does not implement EnvironSource")
+	})
+
+	t.Run("conditional struct", func(t *testing.T) {
+		type config struct {
+			StorageType string `env:"STORAGE_TYPE" default:"s3"`
+			S3          struct {
+				Bucket string `env:"BUCKET,required"`
+			} `env:"S3_" when:"STORAGE_TYPE=s3|minio"`
+			GCS struct {
+				Bucket string `env:"BUCKET,required"`
+			} `env:"GCS_" when:"STORAGE_TYPE=gcs"`
+		}
+
+		var cfg config
+		err := env.Load(&cfg, &env.Options{Source: env.Map{"S3_BUCKET": "foo"}})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.S3.Bucket, "foo")
+
+		cfg = config{}
+		err = env.Load(&cfg, &env.Options{Source: env.Map{"STORAGE_TYPE": "gcs", "S3_BUCKET": "foo"}})
+		assert.Equal[E](t, err.Error(), "env: GCS_BUCKET is required but not set")
+		assert.Equal[E](t, cfg.S3.Bucket, "")
+	})
+
+	t.Run("invalid when tag", func(t *testing.T) {
+		var cfg struct {
+			A struct {
+				Foo int `env:"FOO"`
+			} `when:"A"`
+		}
+		load := func() { _ = env.Load(&cfg, nil) }
+		assert.Panics[E](t, load, "env: invalid `when` tag `A`")
+	})
+
+	t.Run("nested struct w/ separator", func(t *testing.T) {
+		m := env.Map{"A_FOO": "1", "B__BAR": "2", "B__C__BAZ": "3"}
+
+		var cfg struct {
+			A struct {
+				Foo int `env:"FOO"`
+			} `env:"A"`
+			B struct {
+				Bar int `env:"BAR"`
+				C   struct {
+					Baz int `env:"BAZ"`
+				} `env:"C"`
+			} `env:"B,sep=__"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, NameSep: "_"})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.A.Foo, 1)
+		assert.Equal[E](t, cfg.B.Bar, 2)
+		assert.Equal[E](t, cfg.B.C.Baz, 3)
+	})
+
 	t.Run("unsupported type", func(t *testing.T) {
 		m := env.Map{"FOO": "1+2i"}
 
@@ -191,6 +623,362 @@ func TestLoad(t *testing.T) {
 		assert.Equal[E](t, cfg.IPs, []net.IP{net.IPv4zero, net.IPv4bcast})
 	})
 
+	t.Run("file mode", func(t *testing.T) {
+		m := env.Map{"PERM": "0644", "DIR": "1777"}
+
+		var cfg struct {
+			Perm os.FileMode `env:"PERM"`
+			Dir  os.FileMode `env:"DIR"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Perm, os.FileMode(0o644))
+		assert.Equal[E](t, cfg.Dir, os.ModeSticky|0o777)
+
+		var bad struct {
+			Bad os.FileMode `env:"BAD"`
+		}
+		err = env.Load(&bad, &env.Options{Source: env.Map{"BAD": "10000"}})
+		assert.Equal[E](t, err.Error(), `parsing file mode: "10000" has bits other than permission, setuid, setgid, and sticky`)
+	})
+
+	t.Run("time in location", func(t *testing.T) {
+		nyc, err := time.LoadLocation("America/New_York")
+		assert.NoErr[F](t, err)
+
+		var cfg struct {
+			T1 time.Time `env:"T1"`
+			T2 time.Time `env:"T2"`
+		}
+		m := env.Map{"T1": "2024-01-01T09:00:00+03:00", "T2": "2024-01-01 09:00"}
+
+		err = env.Load(&cfg, &env.Options{Source: m, Location: nyc})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.T1.Equal(time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)), true)
+		assert.Equal[E](t, cfg.T2, time.Date(2024, 1, 1, 9, 0, 0, 0, nyc))
+
+		m["TZ"] = "America/New_York"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.T2.Equal(time.Date(2024, 1, 1, 9, 0, 0, 0, nyc)), true)
+
+		delete(m, "TZ")
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.T2, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	})
+
+	t.Run("int base prefix", func(t *testing.T) {
+		m := env.Map{"FLAGS": "0xff", "PERMS": "0o644", "MASK": "0b1010", "BIG": "1_000_000"}
+
+		var cfg struct {
+			Flags uint   `env:"FLAGS"`
+			Perms uint32 `env:"PERMS"`
+			Mask  int    `env:"MASK"`
+			Big   int64  `env:"BIG"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, IntBasePrefix: true})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Flags, 0xff)
+		assert.Equal[E](t, cfg.Perms, 0o644)
+		assert.Equal[E](t, cfg.Mask, 0b1010)
+		assert.Equal[E](t, cfg.Big, 1_000_000)
+
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.IsErr[E](t, err, strconv.ErrSyntax)
+	})
+
+	t.Run("maps", func(t *testing.T) {
+		m := env.Map{
+			"LABELS": "env=prod,team=core",
+			"PORTS":  "http=80,https=443",
+			"DSNS":   `main=host\=db\,port\=5432,path=C:\\data`,
+			"EMPTY":  "",
+		}
+
+		var cfg struct {
+			Labels map[string]string `env:"LABELS"`
+			Ports  map[string]int    `env:"PORTS"`
+			DSNs   map[string]string `env:"DSNS"`
+			Empty  map[string]string `env:"EMPTY"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Labels, map[string]string{"env": "prod", "team": "core"})
+		assert.Equal[E](t, cfg.Ports, map[string]int{"http": 80, "https": 443})
+		assert.Equal[E](t, cfg.DSNs, map[string]string{"main": "host=db,port=5432", "path": `C:\data`})
+		assert.Equal[E](t, cfg.Empty, map[string]string{})
+
+		var custom struct {
+			Labels map[string]string `env:"LABELS"`
+		}
+		err = env.Load(&custom, &env.Options{Source: env.Map{"LABELS": "env:prod;team:core"}, MapSep: ";", KVSep: ":"})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, custom.Labels, map[string]string{"env": "prod", "team": "core"})
+	})
+
+	t.Run("nested slices", func(t *testing.T) {
+		m := env.Map{"GROUPS": "a:1 b:2;c:3", "WEIGHTS": "1,2|3"}
+
+		var cfg struct {
+			Groups  [][]string `env:"GROUPS"`
+			Weights [][]int    `env:"WEIGHTS"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.IsErr[E](t, err, strconv.ErrSyntax)
+
+		m["WEIGHTS"] = "1 2;3"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Groups, [][]string{{"a:1", "b:2"}, {"c:3"}})
+		assert.Equal[E](t, cfg.Weights, [][]int{{1, 2}, {3}})
+
+		m["WEIGHTS"] = "1,2|3"
+		err = env.Load(&cfg, &env.Options{Source: m, SliceSep: ",", GroupSep: "|"})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Weights, [][]int{{1, 2}, {3}})
+	})
+
+	t.Run("pointers", func(t *testing.T) {
+		m := env.Map{"PORT": "8080", "NAME": "", "DEBUG": "false"}
+
+		var cfg struct {
+			Port    *int           `env:"PORT"`
+			Name    *string        `env:"NAME"`
+			Debug   *bool          `env:"DEBUG"`
+			Timeout *time.Duration `env:"TIMEOUT"`
+			Retries *int           `env:"RETRIES" default:"3"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, *cfg.Port, 8080)
+		assert.Equal[E](t, *cfg.Name, "")
+		assert.Equal[E](t, *cfg.Debug, false)
+		assert.Equal[E](t, cfg.Timeout, nil)
+		assert.Equal[E](t, *cfg.Retries, 3)
+
+		m["PORT"] = "http"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.IsErr[E](t, err, strconv.ErrSyntax)
+	})
+
+	t.Run("url", func(t *testing.T) {
+		m := env.Map{"ENDPOINT": "https://example.com/api", "PROXY": "socks5://localhost:1080"}
+
+		var cfg struct {
+			Endpoint url.URL  `env:"ENDPOINT"`
+			Proxy    *url.URL `env:"PROXY"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Endpoint.String(), "https://example.com/api")
+		assert.Equal[E](t, cfg.Proxy.Host, "localhost:1080")
+
+		m["ENDPOINT"] = "example.com"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.Equal[E](t, err.Error(), "parsing url: missing scheme")
+
+		m["ENDPOINT"] = "https://user:secret@[::1"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.Equal[E](t, err.Error(), `parsing url: missing ']' in host`)
+	})
+
+	t.Run("big numbers", func(t *testing.T) {
+		m := env.Map{"SUPPLY": "21000000000000000000000000", "PRICE": "0.1", "RATIO": "1/3"}
+
+		var cfg struct {
+			Supply big.Int    `env:"SUPPLY"`
+			Price  *big.Float `env:"PRICE"`
+			Ratio  big.Rat    `env:"RATIO"`
+			Fee    big.Int    `env:"FEE" default:"100"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Supply.String(), "21000000000000000000000000")
+		assert.Equal[E](t, cfg.Price.String(), "0.1")
+		assert.Equal[E](t, cfg.Ratio.String(), "1/3")
+		assert.Equal[E](t, cfg.Fee.String(), "100")
+
+		m["SUPPLY"] = "1e3"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.Equal[E](t, err.Error(), "unmarshaling text: math/big: cannot unmarshal \"1e3\" into a *big.Int")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		type feature struct {
+			Name    string `json:"name"`
+			Enabled bool   `json:"enabled"`
+		}
+		m := env.Map{
+			"FEATURES": `[{"name":"checkout","enabled":true}]`,
+			"LIMITS":   `{"rps":100}`,
+			"OWNER":    `{"name":"core"}`,
+		}
+
+		var cfg struct {
+			Features []feature          `env:"FEATURES,json"`
+			Limits   map[string]int     `env:"LIMITS,json"`
+			Owner    feature            `env:"OWNER,json"`
+			Teams    map[string]feature `env:"TEAMS,json" default:"{}"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Features, []feature{{Name: "checkout", Enabled: true}})
+		assert.Equal[E](t, cfg.Limits, map[string]int{"rps": 100})
+		assert.Equal[E](t, cfg.Owner, feature{Name: "core"})
+		assert.Equal[E](t, cfg.Teams, map[string]feature{})
+
+		m["LIMITS"] = `{"rps":"100"}`
+		err = env.Load(&cfg, &env.Options{Source: m})
+		var jerr *json.UnmarshalTypeError
+		assert.AsErr[E](t, err, &jerr)
+	})
+
+	t.Run("byte sizes", func(t *testing.T) {
+		m := env.Map{"CACHE": "1.5GiB", "UPLOAD": "10MB", "BUFFER": "512", "SMALL": "1kb"}
+
+		var cfg struct {
+			Cache  int64  `env:"CACHE,size"`
+			Upload uint64 `env:"UPLOAD,size"`
+			Buffer int    `env:"BUFFER,size"`
+			Small  int16  `env:"SMALL,size"`
+			Limit  int    `env:"LIMIT,size" default:"1KiB"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Cache, 1536<<20)
+		assert.Equal[E](t, cfg.Upload, 10_000_000)
+		assert.Equal[E](t, cfg.Buffer, 512)
+		assert.Equal[E](t, cfg.Small, 1000)
+		assert.Equal[E](t, cfg.Limit, 1024)
+
+		m["SMALL"] = "1MB"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.IsErr[E](t, err, strconv.ErrRange)
+
+		m["SMALL"] = "1 parsec"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.Equal[E](t, err.Error(), `parsing size: unknown unit " parsec"`)
+
+		var invalid struct {
+			Size string `env:"SIZE,size"`
+		}
+		load := func() { _ = env.Load(&invalid, nil) }
+		assert.Panics[E](t, load, "env: `size` can't be used with type `string`")
+	})
+
+	t.Run("named parsers", func(t *testing.T) {
+		m := env.Map{"KEY": "cafe", "CERT": "Y2VydA=="}
+		opts := &env.Options{
+			Source: m,
+			Parsers: map[string]func(string) (any, error){
+				"hex":    func(s string) (any, error) { return hex.DecodeString(s) },
+				"base64": func(s string) (any, error) { return base64.StdEncoding.DecodeString(s) },
+				"upper":  func(s string) (any, error) { return strings.ToUpper(s), nil },
+			},
+		}
+
+		var cfg struct {
+			Key  []byte `env:"KEY,parser=hex"`
+			Cert []byte `env:"CERT,parser=base64"`
+			Mode string `env:"MODE,parser=upper" default:"debug"`
+		}
+		err := env.Load(&cfg, opts)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Key, []byte{0xca, 0xfe})
+		assert.Equal[E](t, cfg.Cert, []byte("cert"))
+		assert.Equal[E](t, cfg.Mode, "DEBUG")
+
+		m["KEY"] = "xyz"
+		err = env.Load(&cfg, opts)
+		assert.IsErr[E](t, err, hex.InvalidByteError('x'))
+
+		var mismatch struct {
+			Key string `env:"KEY,parser=hex"`
+		}
+		m["KEY"] = "cafe"
+		err = env.Load(&mismatch, opts)
+		assert.Equal[E](t, err.Error(), "parsing hex: got []uint8, want string")
+
+		load := func() { _ = env.Load(&cfg, nil) }
+		assert.Panics[E](t, load, "env: unknown parser `hex`")
+	})
+
+	t.Run("remainder", func(t *testing.T) {
+		m := env.Map{
+			"MYAPP_PORT":        "8080",
+			"MYAPP_PLUGIN_NAME": "auth",
+			"MYAPP_DB_main_URL": "postgres://",
+			"MYAPP_EXTRA":       "1",
+			"OTHER":             "2",
+		}
+
+		var cfg struct {
+			Port int `env:"MYAPP_PORT"`
+			DBs  map[string]struct {
+				URL string `env:"URL"`
+			} `env:"MYAPP_DB"`
+			Plugin map[string]string `env:",remain,prefix=MYAPP_PLUGIN_"`
+			Rest   map[string]string `env:",remain,prefix=MYAPP_"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Plugin, map[string]string{"MYAPP_PLUGIN_NAME": "auth"})
+		assert.Equal[E](t, cfg.Rest, map[string]string{"MYAPP_EXTRA": "1"})
+
+		var invalid struct {
+			Rest map[string]int `env:",remain"`
+		}
+		load := func() { _ = env.Load(&invalid, nil) }
+		assert.Panics[E](t, load, "env: `remain` can't be used with type `map[string]int`")
+	})
+
+	t.Run("invalid map", func(t *testing.T) {
+		var cfg struct {
+			Labels map[string]string `env:"LABELS"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: env.Map{"LABELS": `foo\=bar`}})
+		assert.Equal[E](t, err.Error(), `parsing map: invalid pair "foo\\=bar"`)
+	})
+
+	t.Run("lenient bool", func(t *testing.T) {
+		m := env.Map{"A": "Yes", "B": "off", "C": "enabled", "D": "N", "E": "1"}
+
+		var cfg struct {
+			A bool   `env:"A"`
+			B bool   `env:"B"`
+			C bool   `env:"C"`
+			D bool   `env:"D"`
+			E []bool `env:"E"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, LenientBool: true})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, []bool{cfg.A, cfg.B, cfg.C, cfg.D}, []bool{true, false, true, false})
+		assert.Equal[E](t, cfg.E, []bool{true})
+
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.IsErr[E](t, err, strconv.ErrSyntax)
+	})
+
+	t.Run("lenient float", func(t *testing.T) {
+		m := env.Map{"A": "0,5", "B": "1.234,5", "C": "1,234.5", "D": "1 234 567", "E": "1.234.567"}
+
+		var cfg struct {
+			A float64 `env:"A"`
+			B float64 `env:"B"`
+			C float64 `env:"C"`
+			D float64 `env:"D"`
+			E float64 `env:"E"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, LenientFloat: true})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.A, 0.5)
+		assert.Equal[E](t, cfg.B, 1234.5)
+		assert.Equal[E](t, cfg.C, 1234.5)
+		assert.Equal[E](t, cfg.D, 1234567.0)
+		assert.Equal[E](t, cfg.E, 1234567.0)
+	})
+
 	t.Run("parsing errors", func(t *testing.T) {
 		tests := map[string]struct {
 			src      env.Source
@@ -213,8 +1001,10 @@ func TestLoad(t *testing.T) {
 				checkErr: func(err error) { assert.IsErr[E](t, err, strconv.ErrSyntax) },
 			},
 			"invalid time.Duration": {
-				src:      env.Map{"DURATION": "-"},
-				checkErr: func(err error) { assert.Equal[E](t, errors.Unwrap(err).Error(), `time: invalid duration "-"`) },
+				src: env.Map{"DURATION": "-"},
+				checkErr: func(err error) {
+					assert.Equal[E](t, errors.Unwrap(errors.Unwrap(err)).Error(), `time: invalid duration "-"`)
+				},
 			},
 			"invalid encoding.TextUnmarshaler": {
 				src:      env.Map{"IP": "-"},
@@ -243,3 +1033,266 @@ func TestLoad(t *testing.T) {
 		}
 	})
 }
+
+func TestLoadAll(t *testing.T) {
+	type DB struct {
+		Host string `env:"HOST,required"`
+		Port int    `env:"PORT" default:"5432"`
+	}
+
+	t.Run("all set", func(t *testing.T) {
+		m := env.Map{"PRIMARY_HOST": "db1", "REPLICA_HOST": "db2", "REPLICA_PORT": "5433"}
+		dbs, err := env.LoadAll[DB]([]string{"PRIMARY_", "REPLICA_"}, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, dbs, []DB{{"db1", 5432}, {"db2", 5433}})
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		m := env.Map{"PRIMARY_HOST": "db1"}
+		_, err := env.LoadAll[DB]([]string{"PRIMARY_", "REPLICA_"}, &env.Options{Source: m})
+		assert.Equal[E](t, err.Error(), "env: REPLICA_HOST is required but not set")
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		loadAll := func() { _, _ = env.LoadAll[int](nil, nil) }
+		assert.Panics[E](t, loadAll, "env: T must be a struct type")
+	})
+}
+
+func TestLoadContext(t *testing.T) {
+	var cfg struct {
+		Foo int `env:"FOO"`
+	}
+
+	t.Run("context source", func(t *testing.T) {
+		type key struct{}
+		ctx := context.WithValue(context.Background(), key{}, "1")
+		src := contextSource(func(ctx context.Context, _ string) (string, bool, error) {
+			return ctx.Value(key{}).(string), true, nil
+		})
+		err := env.LoadContext(ctx, &cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Foo, 1)
+	})
+
+	t.Run("load start hook", func(t *testing.T) {
+		type key struct{}
+		ctx := context.WithValue(context.Background(), key{}, "trace")
+		var got any
+		hooks := env.Hooks{
+			LoadStart: func(ctx context.Context, _ reflect.Type, _ env.Source) func(int, error) {
+				got = ctx.Value(key{})
+				return nil
+			},
+		}
+		err := env.LoadContext(ctx, &cfg, &env.Options{Source: env.Map{"FOO": "1"}, Hooks: hooks})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, got, "trace")
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := env.LoadContext(ctx, &cfg, &env.Options{Source: env.Map{"FOO": "1"}})
+		assert.IsErr[E](t, err, context.Canceled)
+	})
+
+	t.Run("hanging source", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		src := sourceFunc(func(string) (string, bool) {
+			time.Sleep(time.Second)
+			return "1", true
+		})
+		err := env.LoadContext(ctx, &cfg, &env.Options{Source: src})
+		assert.IsErr[E](t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("slow batch source", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		src := slowBatchSource{Map: env.Map{"FOO": "1"}, delay: 500 * time.Millisecond}
+		err := env.LoadContext(ctx, &cfg, &env.Options{Source: src})
+		assert.IsErr[E](t, err, context.DeadlineExceeded)
+
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = env.LoadContext(canceled, &cfg, &env.Options{Source: src})
+		assert.IsErr[E](t, err, context.Canceled)
+	})
+}
+
+func TestConcurrency(t *testing.T) {
+	var cfg struct {
+		A int `env:"A"`
+		B int `env:"B"`
+		C int `env:"C"`
+		D int `env:"D"`
+	}
+
+	t.Run("all set", func(t *testing.T) {
+		var mu sync.Mutex
+		var inflight, peak int
+		src := env.LookupFunc(func(key string) (string, bool, error) {
+			mu.Lock()
+			inflight++
+			if inflight > peak {
+				peak = inflight
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			inflight--
+			mu.Unlock()
+			return "1", true, nil
+		})
+		err := env.Load(&cfg, &env.Options{Source: src, Concurrency: 2})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.D, 1)
+		assert.Equal[E](t, peak, 2)
+	})
+
+	t.Run("deterministic error", func(t *testing.T) {
+		src := env.LookupFunc(func(key string) (string, bool, error) {
+			if key == "A" {
+				return "1", true, nil
+			}
+			return "", false, errors.New(key)
+		})
+		err := env.Load(&cfg, &env.Options{Source: src, Concurrency: 4})
+		assert.Equal[E](t, err.Error(), "B")
+	})
+}
+
+type contextSource func(ctx context.Context, key string) (string, bool, error)
+
+func (fn contextSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := fn(context.Background(), key)
+	return value, ok
+}
+
+func (fn contextSource) LookupEnvContext(ctx context.Context, key string) (string, bool, error) {
+	return fn(ctx, key)
+}
+
+func TestProvide(t *testing.T) {
+	type Config struct {
+		Port int `env:"PORT"`
+	}
+
+	provide := env.Provide[Config](&env.Options{Source: env.Map{"PORT": "8080"}})
+	cfg, err := provide()
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, cfg, Config{Port: 8080})
+
+	notStruct := func() { env.Provide[int](nil) }
+	assert.Panics[E](t, notStruct, "env: T must be a struct type")
+}
+
+func TestLoadValue(t *testing.T) {
+	type Sub struct {
+		Port int `env:"PORT"`
+	}
+	type Config struct {
+		Host  string         `env:"HOST"`
+		Port  int            `env:"PORT"`
+		Hosts []string       `env:"HOSTS"`
+		Subs  map[string]Sub `env:"SUB"`
+	}
+
+	proto := Config{Host: "localhost", Port: 80, Hosts: []string{"a"}, Subs: map[string]Sub{"x": {Port: 1}}}
+	cfg, err := env.LoadValue(proto, &env.Options{Source: env.Map{"PORT": "8080", "HOSTS": "b c", "SUB_y_PORT": "2"}})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, cfg, Config{Host: "localhost", Port: 8080, Hosts: []string{"b", "c"}, Subs: map[string]Sub{"x": {Port: 1}, "y": {Port: 2}}})
+	assert.Equal[E](t, proto, Config{Host: "localhost", Port: 80, Hosts: []string{"a"}, Subs: map[string]Sub{"x": {Port: 1}}})
+
+	notStruct := func() { _, _ = env.LoadValue(1, nil) }
+	assert.Panics[E](t, notStruct, "env: T must be a struct type")
+}
+
+func TestUnknownVars(t *testing.T) {
+	m := env.Map{"APP_HOST": "localhost", "APP_HSOT": "typo", "APP_DB_main_PORT": "5432", "APP_LEGACY": "1", "HOME": "/root"}
+
+	var cfg struct {
+		Host string `env:"APP_HOST"`
+		DBs  map[string]struct {
+			Port int `env:"PORT"`
+		} `env:"APP_DB"`
+	}
+	unknown, err := env.UnknownVars(&cfg, "APP_", &env.Options{Source: m})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, unknown, []string{"APP_HSOT", "APP_LEGACY"})
+
+	_, err = env.UnknownVars(&cfg, "APP_", &env.Options{Source: sourceFunc(nil)})
+	assert.Equal[E](t, err.Error(), "env: the source does not implement EnvironSource")
+}
+
+func TestValidateSpec(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		var cfg struct {
+			Foo int      `env:"FOO" default:"1"`
+			Bar []string `env:"BAR,required"`
+		}
+		err := env.ValidateSpec(&cfg, nil)
+		assert.NoErr[E](t, err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var cfg struct {
+			Foo int         `env:"FOO" default:"one"`
+			Bar int         `env:"BAR,expand,noexpand"`
+			Baz int         `env:"BAZ,required" default:"1"`
+			Qux int         `env:"QUX,unknown"`
+			Ok  int         `env:"OK"`
+			Dup int         `env:"OK"`
+			Ch  chan int    `env:"CH"`
+			M   map[int]any `env:"M"`
+			Sec string      `env:"SEC" source:"vault"`
+			Min int         `env:"MIN,min=1" default:"0"`
+			Max int         `env:"MAX,max=10" default:"11"`
+			Lvl string      `env:"LVL,oneof=debug|info" default:"warn"`
+		}
+		err := env.ValidateSpec(&cfg, nil)
+		assert.Equal[E](t, err.Error(), "env: invalid default value `one` of FOO: parsing int: strconv.ParseInt: parsing \"one\": invalid syntax\n"+
+			"env: `expand` and `noexpand` can't be used simultaneously\n"+
+			"env: `required` and `default` can't be used simultaneously\n"+
+			"env: invalid tag option `unknown`\n"+
+			"env: invalid default value `0` of MIN: 0 is less than min 1\n"+
+			"env: invalid default value `11` of MAX: 11 is greater than max 10\n"+
+			"env: invalid default value `warn` of LVL: value must be one of debug, info\n"+
+			"env: duplicate name `OK` used by fields Ok and Dup\n"+
+			"env: unsupported type `chan int` of CH\n"+
+			"env: unsupported type `map[int]interface {}` of M\n"+
+			"env: unknown source `vault` of SEC")
+	})
+}
+
+func TestPlainStringAllocs(t *testing.T) {
+	type name string
+
+	var plain struct {
+		A string `env:"A"`
+		B string `env:"B"`
+		C string `env:"C" default:"c"`
+		D string `env:"D"`
+	}
+	var named struct {
+		A name `env:"A"`
+		B name `env:"B"`
+		C name `env:"C" default:"c"`
+		D name `env:"D"`
+	}
+
+	opts := &env.Options{Source: env.Map{"A": "a", "B": "b"}}
+	plainAllocs := testing.AllocsPerRun(100, func() { _ = env.Load(&plain, opts) })
+	namedAllocs := testing.AllocsPerRun(100, func() { _ = env.Load(&named, opts) })
+	assert.Equal[E](t, plainAllocs < namedAllocs, true)
+}
+
+type port int
+
+func (port) DefaultEnvValue() string { return "8080" }
+
+type mode string
+
+func (*mode) DefaultEnvValue() string { return "release" }
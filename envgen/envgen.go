@@ -0,0 +1,186 @@
+// Package envgen generates Go code from the config structs used with the [env] package.
+//
+// It is intended to be run from a small program invoked by go:generate, e.g.
+//
+//	//go:build ignore
+//
+//	package main
+//
+//	func main() {
+//		f, _ := os.Create("env_gen.go")
+//		defer f.Close()
+//		if err := envgen.Generate(f, "config", new(config.Config), nil); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package envgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-simpler.org/env"
+)
+
+// Generate writes a Go file for the package pkg with a constant for the name of each environment variable declared in cfg
+// (e.g. const EnvDBHost = "DB_HOST" for the DB.Host field) and a typed getter that loads the variable using [env.Load]
+// (e.g. func DBHost(opts *env.Options) (string, error)), so that the rest of the codebase doesn't reference the variables by string.
+// The getters declare the variables with the same tags as the fields of cfg (except `when`), so the options such as `size` or `min` apply.
+// opts are only used to resolve the names of the variables; pass the same options to the getters to load them the same way as cfg.
+// The types defined in the package of cfg's type are referenced unqualified, so the file is meant to be placed next to cfg.
+// The variables of maps of structs and remainder fields are skipped, since their names are not known in advance.
+// cfg must be a non-nil struct pointer, otherwise Generate panics.
+func Generate(w io.Writer, pkg string, cfg any, opts *env.Options) error {
+	root := reflect.TypeOf(cfg).Elem()
+	g := generator{
+		local:   root.PkgPath(),
+		imports: map[string]struct{}{"go-simpler.org/env": {}},
+	}
+
+	var body bytes.Buffer
+	var consts bytes.Buffer
+	env.VisitVars(cfg, opts, func(v env.Var, _ func() (string, bool, error)) bool {
+		if strings.Contains(v.Name, "<KEY>") || strings.HasSuffix(v.Name, "*") {
+			return true
+		}
+		ident := strings.ReplaceAll(v.Field, ".", "")
+		fmt.Fprintf(&consts, "\tEnv%s = %q\n", ident, v.Name)
+
+		tag := fieldTag(root, v)
+		typ := g.typeString(v.Type)
+
+		fmt.Fprintf(&body, "\n// %s returns the value of the %s environment variable.\n", ident, v.Name)
+		fmt.Fprintf(&body, "func %s(opts *env.Options) (%s, error) {\n", ident, typ)
+		fmt.Fprintf(&body, "\tvar cfg struct {\n\t\tV %s %s\n\t}\n", typ, strconv.Quote(tag))
+		fmt.Fprintf(&body, "\terr := env.Load(&cfg, opts)\n\treturn cfg.V, err\n}\n")
+		return true
+	})
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by go-simpler.org/env/envgen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	var std, other []string
+	for path := range g.imports {
+		if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	for _, path := range std {
+		fmt.Fprintf(&src, "\t%q\n", path)
+	}
+	if len(std) > 0 {
+		src.WriteString("\n")
+	}
+	for _, path := range other {
+		fmt.Fprintf(&src, "\t%q\n", path)
+	}
+	fmt.Fprintf(&src, ")\n\n// The names of the environment variables.\nconst (\n%s)\n%s", consts.String(), body.String())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("envgen: formatting source: %w", err)
+	}
+	if _, err := w.Write(formatted); err != nil {
+		return fmt.Errorf("envgen: writing source: %w", err)
+	}
+	return nil
+}
+
+type generator struct {
+	local   string // the path of the package the code is generated for.
+	imports map[string]struct{}
+}
+
+// typeString returns the Go expression of the type, collecting the imports.
+func (g *generator) typeString(t reflect.Type) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" || t.PkgPath() == g.local {
+			return t.Name()
+		}
+		name, _, _ := strings.Cut(t.String(), ".")
+		g.imports[t.PkgPath()] = struct{}{}
+		return name + "." + t.Name()
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		return "[]" + g.typeString(t.Elem())
+	case reflect.Map:
+		return "map[" + g.typeString(t.Key()) + "]" + g.typeString(t.Elem())
+	case reflect.Ptr:
+		return "*" + g.typeString(t.Elem())
+	default:
+		return t.String()
+	}
+}
+
+// fieldTag returns the tag of the struct field declaring the variable, with the `env` name replaced by the full name of the variable.
+// The default value taken from the struct field is added as the `default` tag, if possible.
+func fieldTag(t reflect.Type, v env.Var) string {
+	var tag reflect.StructTag
+	for _, name := range strings.Split(v.Field, ".") {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		sf, _ := t.FieldByName(name)
+		tag, t = sf.Tag, sf.Type
+	}
+
+	var parts []string
+	for s := strings.TrimSpace(string(tag)); s != ""; s = strings.TrimSpace(s) {
+		key, rest, ok := strings.Cut(s, ":")
+		if !ok {
+			break
+		}
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			break
+		}
+		s = rest[len(quoted):]
+		switch key {
+		case "env":
+			value, _ := strconv.Unquote(quoted)
+			if _, options, ok := strings.Cut(value, ","); ok {
+				quoted = strconv.Quote(v.Name + "," + options)
+			} else {
+				quoted = strconv.Quote(v.Name)
+			}
+		case "when":
+			continue // the conditions refer to the other variables of cfg.
+		}
+		parts = append(parts, key+":"+quoted)
+	}
+
+	if _, ok := tag.Lookup("default"); !ok && !v.Required && validDefault(v) {
+		parts = append(parts, "default:"+strconv.Quote(v.Default))
+	}
+	return strings.Join(parts, " ")
+}
+
+// validDefault reports whether the default value of the variable can be used as the `default` tag,
+// since the defaults taken from the struct fields are formatted with fmt and may not be parsable.
+func validDefault(v env.Var) (ok bool) {
+	if v.Default == "" {
+		return false
+	}
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	typ := reflect.StructOf([]reflect.StructField{{
+		Name: "V",
+		Type: v.Type,
+		Tag:  reflect.StructTag(`env:"V"`),
+	}})
+	cfg := reflect.New(typ).Interface()
+	return env.Load(cfg, &env.Options{Source: env.Map{"V": v.Default}, NoCache: true}) == nil
+}
@@ -0,0 +1,109 @@
+package envgen_test
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"go-simpler.org/env/envgen"
+)
+
+type level string
+
+type config struct {
+	DB struct {
+		Host string `env:"HOST,required"`
+		Port int    `env:"PORT" default:"5432"`
+	} `env:"DB_"`
+	Timeout time.Duration `env:"TIMEOUT" default:"5s"`
+	Allow   []net.IP      `env:"ALLOW"`
+	Level   level         `env:"LEVEL"`
+	Limit   int64         `env:"LIMIT,size" default:"1KiB" usage:"the upload limit"`
+}
+
+func TestGenerate(t *testing.T) {
+	var buf bytes.Buffer
+	if err := envgen.Generate(&buf, "envgen_test", new(config), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `// Code generated by go-simpler.org/env/envgen. DO NOT EDIT.
+
+package envgen_test
+
+import (
+	"net"
+	"time"
+
+	"go-simpler.org/env"
+)
+
+// The names of the environment variables.
+const (
+	EnvDBHost  = "DB_HOST"
+	EnvDBPort  = "DB_PORT"
+	EnvTimeout = "TIMEOUT"
+	EnvAllow   = "ALLOW"
+	EnvLevel   = "LEVEL"
+	EnvLimit   = "LIMIT"
+)
+
+// DBHost returns the value of the DB_HOST environment variable.
+func DBHost(opts *env.Options) (string, error) {
+	var cfg struct {
+		V string "env:\"DB_HOST,required\""
+	}
+	err := env.Load(&cfg, opts)
+	return cfg.V, err
+}
+
+// DBPort returns the value of the DB_PORT environment variable.
+func DBPort(opts *env.Options) (int, error) {
+	var cfg struct {
+		V int "env:\"DB_PORT\" default:\"5432\""
+	}
+	err := env.Load(&cfg, opts)
+	return cfg.V, err
+}
+
+// Timeout returns the value of the TIMEOUT environment variable.
+func Timeout(opts *env.Options) (time.Duration, error) {
+	var cfg struct {
+		V time.Duration "env:\"TIMEOUT\" default:\"5s\""
+	}
+	err := env.Load(&cfg, opts)
+	return cfg.V, err
+}
+
+// Allow returns the value of the ALLOW environment variable.
+func Allow(opts *env.Options) ([]net.IP, error) {
+	var cfg struct {
+		V []net.IP "env:\"ALLOW\""
+	}
+	err := env.Load(&cfg, opts)
+	return cfg.V, err
+}
+
+// Level returns the value of the LEVEL environment variable.
+func Level(opts *env.Options) (level, error) {
+	var cfg struct {
+		V level "env:\"LEVEL\""
+	}
+	err := env.Load(&cfg, opts)
+	return cfg.V, err
+}
+
+// Limit returns the value of the LIMIT environment variable.
+func Limit(opts *env.Options) (int64, error) {
+	var cfg struct {
+		V int64 "env:\"LIMIT,size\" default:\"1KiB\" usage:\"the upload limit\""
+	}
+	err := env.Load(&cfg, opts)
+	return cfg.V, err
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("generated code mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+// Package envtest provides utilities for testing the config structs used with the [env] package.
+package envtest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-simpler.org/env"
+)
+
+var update = flag.Bool("env.update", false, "update the golden files of envtest.GoldenUsage")
+
+// GoldenUsage renders the [env.Usage] message of the given struct and compares it with the content of the golden file.
+// If the test binary is run with the -env.update flag, the golden file is created or overwritten instead.
+// It allows guarding the documented environment against accidental changes.
+func GoldenUsage(tb testing.TB, cfg any, path string, opts *env.Options) {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	env.Usage(cfg, &buf, opts)
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("envtest: creating directory: %v", err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			tb.Fatalf("envtest: writing golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("envtest: reading golden file: %v (run with -env.update to create it)", err)
+	}
+	if got := buf.String(); got != string(want) {
+		tb.Errorf("envtest: usage message does not match %s (run with -env.update to update it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
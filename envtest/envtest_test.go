@@ -0,0 +1,14 @@
+package envtest_test
+
+import (
+	"testing"
+
+	"go-simpler.org/env/envtest"
+)
+
+func TestGoldenUsage(t *testing.T) {
+	var cfg struct {
+		Port int `env:"PORT" default:"8080" usage:"http port"`
+	}
+	envtest.GoldenUsage(t, &cfg, "testdata/usage.golden", nil)
+}
@@ -158,6 +158,26 @@ func ExampleLoad_sliceSeparator() {
 	// Output: [8080 8081 8082]
 }
 
+func ExampleLoad_mapOfStructs() {
+	os.Setenv("DB_PRIMARY_HOST", "db1")
+	os.Setenv("DB_REPLICA_HOST", "db2")
+
+	var cfg struct {
+		DBs map[string]struct {
+			Host string `env:"HOST"`
+		} `env:"DB"`
+	}
+	if err := env.Load(&cfg, nil); err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Println(cfg.DBs["PRIMARY"].Host)
+	fmt.Println(cfg.DBs["REPLICA"].Host)
+	// Output:
+	// db1
+	// db2
+}
+
 func ExampleUsage() {
 	os.Unsetenv("DB_HOST")
 	os.Unsetenv("DB_PORT")
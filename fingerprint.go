@@ -0,0 +1,34 @@
+package env
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+)
+
+// Fingerprint returns a stable hash of the current values of the environment variables declared in the given struct.
+// It can be used to detect configuration changes between deployments or to compare the configuration of replicas.
+// The values of the variables marked as secret are hashed separately, so they cannot be recovered from the result.
+// cfg must be a non-nil struct pointer, otherwise Fingerprint panics.
+// The caller must pass the same [Options] to both [Load] and [Fingerprint], or nil.
+func Fingerprint(cfg any, opts *Options) string {
+	pv := reflect.ValueOf(cfg)
+	if !structPtr(pv) {
+		panic("env: cfg must be a non-nil struct pointer")
+	}
+
+	opts = setDefaultOptions(opts)
+
+	h := sha256.New()
+	for _, v := range parseVars(pv.Elem(), opts) {
+		value := formatField(v.structField)
+		if v.Secret {
+			sum := sha256.Sum256([]byte(value))
+			value = hex.EncodeToString(sum[:])
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00", v.Name, value)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
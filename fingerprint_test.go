@@ -0,0 +1,56 @@
+package env_test
+
+import (
+	"net/url"
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestFingerprint(t *testing.T) {
+	type config struct {
+		Port     int    `env:"PORT"`
+		Password string `env:"PASSWORD,secret"`
+	}
+
+	load := func(m env.Map) string {
+		var cfg config
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		return env.Fingerprint(&cfg, nil)
+	}
+
+	a := load(env.Map{"PORT": "8080", "PASSWORD": "foo"})
+	b := load(env.Map{"PORT": "8080", "PASSWORD": "foo"})
+	c := load(env.Map{"PORT": "8080", "PASSWORD": "bar"})
+	d := load(env.Map{"PORT": "8081", "PASSWORD": "foo"})
+
+	assert.Equal[E](t, a, b)
+	assert.Equal[E](t, a != c, true)
+	assert.Equal[E](t, a != d, true)
+	assert.Equal[E](t, len(a), 64)
+
+	t.Run("pointers", func(t *testing.T) {
+		type config struct {
+			Port     *int     `env:"PORT"`
+			Endpoint *url.URL `env:"ENDPOINT"`
+			Timeout  *int     `env:"TIMEOUT"`
+		}
+
+		load := func(m env.Map) string {
+			var cfg config
+			err := env.Load(&cfg, &env.Options{Source: m})
+			assert.NoErr[F](t, err)
+			return env.Fingerprint(&cfg, nil)
+		}
+
+		a := load(env.Map{"PORT": "8080", "ENDPOINT": "https://example.com"})
+		b := load(env.Map{"PORT": "8080", "ENDPOINT": "https://example.com"})
+		c := load(env.Map{"PORT": "8081", "ENDPOINT": "https://example.com"})
+
+		assert.Equal[E](t, a, b)
+		assert.Equal[E](t, a != c, true)
+	})
+}
@@ -0,0 +1,131 @@
+package env
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FeatureFlags provides access to boolean toggles that don't fit a static struct, see [Flags].
+// It is safe for concurrent use.
+type FeatureFlags struct {
+	prefix string
+	opts   *Options
+
+	mu     sync.Mutex
+	cache  map[string]bool
+	subs   map[int]func(name string, enabled bool)
+	nextID int
+}
+
+// Flags returns a [FeatureFlags] that reads the flags from [Options.Source],
+// e.g. Flags("FF_", nil).Enabled("new_checkout") reports whether FF_NEW_CHECKOUT is true.
+// The values are parsed as bools (see [Options.LenientBool]); unset, invalid, and failed lookups mean disabled.
+// The values are cached until [FeatureFlags.Refresh] is called.
+func Flags(prefix string, opts *Options) *FeatureFlags {
+	return &FeatureFlags{
+		prefix: prefix,
+		opts:   setDefaultOptions(opts),
+		cache:  make(map[string]bool),
+		subs:   make(map[int]func(string, bool)),
+	}
+}
+
+// Enabled reports whether the flag with the given name is enabled.
+// The name is upper-cased and prefixed to get the name of the environment variable.
+func (f *FeatureFlags) Enabled(name string) bool {
+	name = strings.ToUpper(name)
+
+	f.mu.Lock()
+	enabled, ok := f.cache[name]
+	f.mu.Unlock()
+	if ok {
+		return enabled
+	}
+
+	enabled = f.lookup(name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cache[name] = enabled
+	return enabled
+}
+
+// Subscribe registers fn to be called when a cached flag changes its value on [FeatureFlags.Refresh].
+// A panic in fn is recovered and reported to [Hooks.SubscriberPanic], so it does not affect other subscribers.
+// The returned function unregisters fn.
+func (f *FeatureFlags) Subscribe(fn func(name string, enabled bool)) (unsubscribe func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := f.nextID
+	f.nextID++
+	f.subs[id] = fn
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, id)
+	}
+}
+
+// Refresh re-reads the cached flags and notifies the subscribers about the changed ones.
+// It can be called periodically, e.g. from a [time.Ticker] loop.
+func (f *FeatureFlags) Refresh() {
+	f.mu.Lock()
+	names := make([]string, 0, len(f.cache))
+	for name := range f.cache {
+		names = append(names, name)
+	}
+	f.mu.Unlock()
+	sort.Strings(names)
+
+	values := make(map[string]bool, len(names))
+	for _, name := range names {
+		values[name] = f.lookup(name)
+	}
+
+	f.mu.Lock()
+	var changed []string
+	for _, name := range names {
+		if f.cache[name] != values[name] {
+			changed = append(changed, name)
+		}
+		f.cache[name] = values[name]
+	}
+	subs := make([]func(string, bool), 0, len(f.subs))
+	for id := 0; id < f.nextID; id++ {
+		if fn, ok := f.subs[id]; ok {
+			subs = append(subs, fn)
+		}
+	}
+	f.mu.Unlock()
+
+	for _, name := range changed {
+		for _, fn := range subs {
+			f.notify(fn, name, values[name])
+		}
+	}
+}
+
+func (f *FeatureFlags) lookup(name string) bool {
+	value, ok, err := lookupEnv(f.opts, f.prefix+name, false)
+	if err != nil || !ok {
+		return false
+	}
+	var enabled bool
+	if err := setBool(reflect.ValueOf(&enabled).Elem(), value, f.opts); err != nil {
+		return false
+	}
+	return enabled
+}
+
+func (f *FeatureFlags) notify(fn func(string, bool), name string, enabled bool) {
+	defer func() {
+		if r := recover(); r != nil && f.opts.Hooks.SubscriberPanic != nil {
+			f.opts.Hooks.SubscriberPanic(r)
+		}
+	}()
+	fn(name, enabled)
+}
@@ -0,0 +1,41 @@
+package env_test
+
+import (
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestFlags(t *testing.T) {
+	m := env.Map{"FF_NEW_CHECKOUT": "on", "FF_BETA": "false", "FF_BROKEN": "maybe"}
+
+	flags := env.Flags("FF_", &env.Options{Source: m, LenientBool: true})
+	assert.Equal[E](t, flags.Enabled("new_checkout"), true)
+	assert.Equal[E](t, flags.Enabled("beta"), false)
+	assert.Equal[E](t, flags.Enabled("broken"), false)
+	assert.Equal[E](t, flags.Enabled("unset"), false)
+
+	type change struct {
+		name    string
+		enabled bool
+	}
+	var changes []change
+	unsubscribe := flags.Subscribe(func(name string, enabled bool) {
+		changes = append(changes, change{name, enabled})
+	})
+
+	m["FF_BETA"] = "true"
+	m["FF_NEW_CHECKOUT"] = "yes"
+	assert.Equal[E](t, flags.Enabled("beta"), false) // cached.
+
+	flags.Refresh()
+	assert.Equal[E](t, flags.Enabled("beta"), true)
+	assert.Equal[E](t, changes, []change{{"BETA", true}})
+
+	unsubscribe()
+	m["FF_BETA"] = "false"
+	flags.Refresh()
+	assert.Equal[E](t, len(changes), 1)
+}
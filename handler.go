@@ -0,0 +1,103 @@
+package env
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// redacted replaces the values of the variables marked as secret.
+const redacted = "<redacted>"
+
+// Handler returns an [http.Handler] that serves the environment variables declared in the given struct
+// along with their current values, e.g. for mounting under /debug/config.
+// The values of the variables marked as secret are redacted.
+// The response is HTML if the request accepts text/html, and JSON otherwise.
+// cfg must be a non-nil struct pointer, otherwise Handler panics.
+func Handler(cfg any, opts *Options) http.Handler {
+	pv := reflect.ValueOf(cfg)
+	if !structPtr(pv) {
+		panic("env: cfg must be a non-nil struct pointer")
+	}
+
+	opts = setDefaultOptions(opts)
+	msgs := messagesOf(opts)
+	msgs.Headers = msgs.Headers.or(htmlHeaders)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := debugVars(pv.Elem(), opts, "")
+		if strings.Contains(r.Header.Get("Accept"), "text/html") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_ = debugTemplate.Execute(w, struct {
+				Vars     []debugVar
+				Messages Messages
+			}{vars, msgs})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(vars)
+	})
+}
+
+type debugVar struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Usage    string `json:"usage,omitempty"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required"`
+	Secret   bool   `json:"secret"`
+	Value    string `json:"value"`
+}
+
+// debugVars returns the variables declared in v, expanding the maps of structs one element at a time,
+// so that the secret fields of the elements are redacted too.
+func debugVars(v reflect.Value, opts *Options, prefix string) []debugVar {
+	var dvars []debugVar
+	for _, v := range parseVars(v, opts) {
+		name := prefix + v.Name
+		if v.dynamic {
+			sep := autoSep(opts)
+			keys := make(map[string]struct{}, v.structField.Len())
+			for _, key := range v.structField.MapKeys() {
+				keys[key.String()] = struct{}{}
+			}
+			for _, key := range sortedKeys(keys) {
+				elem := reflect.New(v.Type.Elem()).Elem()
+				elem.Set(v.structField.MapIndex(reflect.ValueOf(key).Convert(v.Type.Key())))
+				dvars = append(dvars, debugVars(elem, opts, name+sep+key+sep)...)
+			}
+			continue
+		}
+		value := formatField(v.structField)
+		if v.Secret {
+			value = redacted
+			// the default may be the current value of the field, see [DefaultFromField].
+			if v.Default != "" {
+				v.Default = redacted
+			}
+		}
+		dvars = append(dvars, debugVar{
+			Name:     name,
+			Type:     v.Type.String(),
+			Usage:    v.Usage,
+			Default:  v.Default,
+			Required: v.Required,
+			Secret:   v.Secret,
+			Value:    value,
+		})
+	}
+	return dvars
+}
+
+var debugTemplate = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<table>
+{{- with .Messages.Headers}}
+<tr><th>{{.Name}}</th><th>{{.Type}}</th><th>{{.Value}}</th><th>{{.Default}}</th><th>{{.Usage}}</th></tr>
+{{- end}}
+{{- range .Vars}}
+<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{.Value}}</td><td>{{if .Required}}{{$.Messages.Required}}{{else}}{{.Default}}{{end}}</td><td>{{.Usage}}</td></tr>
+{{- end}}
+</table>
+`))
@@ -0,0 +1,89 @@
+package env_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestHandler(t *testing.T) {
+	cfg := struct {
+		Port     int    `env:"PORT" default:"8080" usage:"http port"`
+		Password string `env:"PASSWORD,required,secret"`
+	}{Port: 80, Password: "foo"}
+
+	h := env.Handler(&cfg, nil)
+
+	t.Run("json", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal[E](t, w.Header().Get("Content-Type"), "application/json")
+		assert.Equal[E](t, w.Body.String(), `[{"name":"PORT","type":"int","usage":"http port","default":"8080","required":false,"secret":false,"value":"80"},`+
+			`{"name":"PASSWORD","type":"string","required":true,"secret":true,"value":"\u003credacted\u003e"}]`+"\n")
+	})
+
+	t.Run("pointers", func(t *testing.T) {
+		var cfg struct {
+			Port     *int    `env:"PORT"`
+			Endpoint url.URL `env:"ENDPOINT"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: env.Map{"PORT": "8080", "ENDPOINT": "https://example.com"}})
+		assert.NoErr[F](t, err)
+
+		w := httptest.NewRecorder()
+		env.Handler(&cfg, &env.Options{DefaultMode: env.DefaultFromZero}).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal[E](t, w.Body.String(), `[{"name":"PORT","type":"*int","required":false,"secret":false,"value":"8080"},`+
+			`{"name":"ENDPOINT","type":"url.URL","required":false,"secret":false,"value":"https://example.com"}]`+"\n")
+	})
+
+	t.Run("map of structs", func(t *testing.T) {
+		type db struct {
+			Host     string `env:"HOST"`
+			Password string `env:"PASSWORD,secret"`
+		}
+		cfg := struct {
+			DBs map[string]db `env:"DB"`
+		}{DBs: map[string]db{"PRIMARY": {Host: "h", Password: "hunter2"}, "REPLICA": {Host: "r", Password: "hunter3"}}}
+
+		w := httptest.NewRecorder()
+		env.Handler(&cfg, nil).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal[E](t, strings.Contains(w.Body.String(), "hunter"), false)
+		assert.Equal[E](t, w.Body.String(), `[{"name":"DB_PRIMARY_HOST","type":"string","default":"h","required":false,"secret":false,"value":"h"},`+
+			`{"name":"DB_PRIMARY_PASSWORD","type":"string","default":"\u003credacted\u003e","required":false,"secret":true,"value":"\u003credacted\u003e"},`+
+			`{"name":"DB_REPLICA_HOST","type":"string","default":"r","required":false,"secret":false,"value":"r"},`+
+			`{"name":"DB_REPLICA_PASSWORD","type":"string","default":"\u003credacted\u003e","required":false,"secret":true,"value":"\u003credacted\u003e"}]`+"\n")
+	})
+
+	t.Run("loaded secret", func(t *testing.T) {
+		var cfg struct {
+			Token string `env:"TOKEN,secret"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: env.Map{"TOKEN": "s3cr3t"}})
+		assert.NoErr[F](t, err)
+
+		w := httptest.NewRecorder()
+		env.Handler(&cfg, nil).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal[E](t, strings.Contains(w.Body.String(), "s3cr3t"), false)
+		assert.Equal[E](t, w.Body.String(), `[{"name":"TOKEN","type":"string","default":"\u003credacted\u003e","required":false,"secret":true,"value":"\u003credacted\u003e"}]`+"\n")
+	})
+
+	t.Run("html", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", "text/html")
+		h.ServeHTTP(w, r)
+		assert.Equal[E](t, w.Header().Get("Content-Type"), "text/html; charset=utf-8")
+		assert.Equal[E](t, w.Body.String(), `<!DOCTYPE html>
+<table>
+<tr><th>Name</th><th>Type</th><th>Value</th><th>Default</th><th>Usage</th></tr>
+<tr><td>PORT</td><td>int</td><td>80</td><td>8080</td><td>http port</td></tr>
+<tr><td>PASSWORD</td><td>string</td><td>&lt;redacted&gt;</td><td>required</td><td></td></tr>
+</table>
+`)
+	})
+}
@@ -0,0 +1,110 @@
+package env
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Holder holds a config of type T that can be refreshed while being read concurrently.
+// The config is never modified in place: each refresh stores a new copy.
+type Holder[T any] struct {
+	opts *Options
+	cfg  atomic.Pointer[T]
+	mu   sync.Mutex // serializes refreshes.
+}
+
+// NewHolder loads a new T using [Load] and returns a [Holder] for it.
+// T must be a struct type, otherwise NewHolder panics.
+func NewHolder[T any](opts *Options) (*Holder[T], error) {
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() != reflect.Struct {
+		panic("env: T must be a struct type")
+	}
+
+	opts = setDefaultOptions(opts)
+
+	cfg := new(T)
+	if err := Load(cfg, opts); err != nil {
+		return nil, err
+	}
+
+	h := &Holder[T]{opts: opts}
+	h.cfg.Store(cfg)
+	return h, nil
+}
+
+// Get returns the current config. The caller must not modify it.
+func (h *Holder[T]) Get() *T {
+	return h.cfg.Load()
+}
+
+// StartRefresh starts re-fetching the variables declared with the `refresh:"DURATION"` tag
+// from [Options.Source] every DURATION, e.g. to support rotation of credentials without restart.
+// If a variable is not set anymore, its previous value is kept.
+// Errors are reported to [Hooks.RefreshError]. The returned function stops refreshing.
+func (h *Holder[T]) StartRefresh() (stop func()) {
+	intervals := make(map[time.Duration][]string)
+	for _, v := range parseVars(reflect.ValueOf(h.Get()).Elem(), h.opts) {
+		if v.refresh > 0 {
+			intervals[v.refresh] = append(intervals[v.refresh], v.Name)
+		}
+	}
+
+	done := make(chan struct{})
+	for interval, names := range intervals {
+		go func(interval time.Duration, names []string) {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					h.Refresh(names...)
+				case <-done:
+					return
+				}
+			}
+		}(interval, names)
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Refresh re-fetches the given variables immediately and stores the updated copy of the config.
+// If no names are given, all variables declared with the `refresh` tag are re-fetched.
+func (h *Holder[T]) Refresh(names ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cfg := *h.Get()
+	for _, v := range parseVars(reflect.ValueOf(&cfg).Elem(), h.opts) {
+		if !v.shouldRefresh(names) {
+			continue
+		}
+		value, ok, err := lookupEnv(routeOpts(h.opts, v), v.Name, v.Expand)
+		if err == nil && ok {
+			old := reflect.New(v.Type).Elem()
+			old.Set(v.structField)
+			if err = assign(v, v.Name, value, true, h.opts); err != nil {
+				v.structField.Set(old) // keep the previous valid value.
+			}
+		}
+		if err != nil && h.opts.Hooks.RefreshError != nil {
+			h.opts.Hooks.RefreshError(v.Name, err)
+		}
+	}
+	h.cfg.Store(&cfg)
+}
+
+func (v Var) shouldRefresh(names []string) bool {
+	if len(names) == 0 {
+		return v.refresh > 0
+	}
+	for _, name := range names {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package env_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestHolder(t *testing.T) {
+	type Config struct {
+		Password string `env:"DB_PASSWORD" refresh:"1h"`
+		Port     int    `env:"DB_PORT" refresh:"1h"`
+		Host     string `env:"DB_HOST"`
+	}
+
+	m := env.Map{"DB_PASSWORD": "foo", "DB_PORT": "5432", "DB_HOST": "localhost"}
+
+	var refreshErr error
+	opts := &env.Options{
+		Source: m,
+		Hooks:  env.Hooks{RefreshError: func(_ string, err error) { refreshErr = err }},
+	}
+
+	h, err := env.NewHolder[Config](opts)
+	assert.NoErr[F](t, err)
+	stop := h.StartRefresh()
+	defer stop()
+
+	old := h.Get()
+	m["DB_PASSWORD"] = "bar"
+	m["DB_HOST"] = "example.com"
+	m["DB_PORT"] = "-"
+	h.Refresh()
+
+	assert.Equal[E](t, *h.Get(), Config{Password: "bar", Port: 5432, Host: "localhost"})
+	assert.Equal[E](t, old.Password, "foo")
+	assert.IsErr[E](t, refreshErr, strconv.ErrSyntax)
+}
+
+func TestHolder_Refresh(t *testing.T) {
+	type Config struct {
+		Key   string `env:"KEY,unescape" refresh:"1h"`
+		Token string `env:"TOKEN" refresh:"1h" maxlen:"3"`
+	}
+
+	m := env.Map{"KEY": `a\nb`, "TOKEN": "foo"}
+
+	var refreshErr error
+	opts := &env.Options{
+		Source: m,
+		Hooks:  env.Hooks{RefreshError: func(_ string, err error) { refreshErr = err }},
+	}
+
+	h, err := env.NewHolder[Config](opts)
+	assert.NoErr[F](t, err)
+
+	m["KEY"] = `c\nd`
+	m["TOKEN"] = "toolong"
+	h.Refresh()
+
+	assert.Equal[E](t, *h.Get(), Config{Key: "c\nd", Token: "foo"})
+	assert.Equal[E](t, refreshErr.Error(), "env: TOKEN: value exceeds the maximum length of 3 bytes")
+}
+
+func TestHolder_StartRefresh(t *testing.T) {
+	type Config struct {
+		Token string `env:"TOKEN" refresh:"1ms"`
+	}
+
+	src := &syncSource{m: env.Map{"TOKEN": "foo"}}
+	h, err := env.NewHolder[Config](&env.Options{Source: src})
+	assert.NoErr[F](t, err)
+	stop := h.StartRefresh()
+	defer stop()
+
+	src.set("TOKEN", "bar")
+	deadline := time.Now().Add(time.Second)
+	for h.Get().Token != "bar" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal[E](t, h.Get().Token, "bar")
+}
+
+type syncSource struct {
+	mu sync.Mutex
+	m  env.Map
+}
+
+func (s *syncSource) LookupEnv(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m.LookupEnv(key)
+}
+
+func (s *syncSource) set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = value
+}
@@ -0,0 +1,128 @@
+package env
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Properties returns a [Map] with the variables parsed from the Java .properties file at path.
+// The keys are converted to the environment variable names by replacing dots and dashes with underscores
+// and upper-casing them, e.g. db.host becomes DB_HOST.
+// The key and the value can be separated by =, :, or whitespace; the lines starting with # or ! are comments,
+// and a trailing backslash continues the value on the next line. The \t, \n, \r, \f, and \uXXXX escapes are supported.
+// Use [IgnoreMissing] for the optional files.
+func Properties(path string) (Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("env: reading file: %w", err)
+	}
+	m, err := parseProperties(data)
+	if err != nil {
+		return nil, fmt.Errorf("env: %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// propertyKeyReplacer converts the .properties keys to the environment variable names.
+var propertyKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+func parseProperties(data []byte) (Map, error) {
+	m := make(Map)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		start := n
+		line := strings.TrimLeft(sc.Text(), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// join the continuation lines.
+		for continued(line) && sc.Scan() {
+			n++
+			line = line[:len(line)-1] + strings.TrimLeft(sc.Text(), " \t\f")
+		}
+
+		key, value := splitProperty(line)
+		key, err := unescapeProperty(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		value, err = unescapeProperty(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		m[strings.ToUpper(propertyKeyReplacer.Replace(key))] = value
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading properties: %w", err)
+	}
+	return m, nil
+}
+
+// continued reports whether the line ends with an odd number of backslashes.
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits the line at the first unescaped separator: =, :, or whitespace.
+func splitProperty(line string) (key, value string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			rest := strings.TrimLeft(line[i:], " \t\f")
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+				rest = rest[1:]
+			}
+			return line[:i], strings.TrimLeft(rest, " \t\f")
+		}
+	}
+	return line, ""
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid escape %q", s[i-1:])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape %q", s[i-1:i+5])
+			}
+			sb.WriteRune(rune(r))
+			i += 4
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,46 @@
+package env_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestProperties(t *testing.T) {
+	const data = `# database
+! another comment
+db.host = localhost
+db.port:5432
+server.read-timeout 5s
+greeting=hello\tw\u00f6rld
+hosts = a,\
+        b,\
+        c
+key\=with\:separators=value
+empty
+`
+	path := filepath.Join(t.TempDir(), "app.properties")
+	err := os.WriteFile(path, []byte(data), 0o600)
+	assert.NoErr[F](t, err)
+
+	m, err := env.Properties(path)
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, m, env.Map{
+		"DB_HOST":             "localhost",
+		"DB_PORT":             "5432",
+		"SERVER_READ_TIMEOUT": "5s",
+		"GREETING":            "hello\twörld",
+		"HOSTS":               "a,b,c",
+		"KEY=WITH:SEPARATORS": "value",
+		"EMPTY":               "",
+	})
+
+	err = os.WriteFile(path, []byte("a=1\nb=\\u00zz\n"), 0o600)
+	assert.NoErr[F](t, err)
+	_, err = env.Properties(path)
+	assert.Equal[E](t, err.Error(), "env: "+path+`: line 2: invalid escape "\\u00zz"`)
+}
@@ -0,0 +1,47 @@
+package env
+
+import "errors"
+
+// Provider adapts a [Source] to the koanf.Provider interface, e.g. k.Load(env.Provider{Source: src}, nil).
+// It can also be used with viper: v.MergeConfigMap(m), where m is the result of [Provider.Read].
+// This allows reusing the sources of this package in existing config stacks.
+type Provider struct {
+	Source    Source                  // The source to read the variables from.
+	Keys      []string                // The keys to read. If empty, Source must implement [EnvironSource].
+	Transform func(key string) string // The optional function to convert the keys, e.g. DB_HOST to db.host.
+}
+
+// Read returns the variables from the source as a map.
+// It implements the koanf.Provider interface.
+func (p Provider) Read() (map[string]any, error) {
+	keys := p.Keys
+	if len(keys) == 0 {
+		var ok bool
+		if keys, ok = environKeys(p.Source); !ok {
+			return nil, errors.New("env: the source does not implement EnvironSource, Provider.Keys must be set")
+		}
+	}
+
+	m := make(map[string]any, len(keys))
+	for _, key := range keys {
+		value, ok, err := lookup(p.Source, key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if p.Transform != nil {
+			key = p.Transform(key)
+		}
+		m[key] = value
+	}
+
+	return m, nil
+}
+
+// ReadBytes is not supported, since a [Source] has no raw representation.
+// It implements the koanf.Provider interface.
+func (Provider) ReadBytes() ([]byte, error) {
+	return nil, errors.New("env: Provider does not support ReadBytes")
+}
@@ -0,0 +1,39 @@
+package env_test
+
+import (
+	"strings"
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestProvider(t *testing.T) {
+	m := env.Map{"DB_HOST": "localhost", "DB_PORT": "5432"}
+
+	t.Run("all keys", func(t *testing.T) {
+		p := env.Provider{
+			Source: m,
+			Transform: func(key string) string {
+				return strings.ReplaceAll(strings.ToLower(key), "_", ".")
+			},
+		}
+		got, err := p.Read()
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, got, map[string]any{"db.host": "localhost", "db.port": "5432"})
+	})
+
+	t.Run("selected keys", func(t *testing.T) {
+		p := env.Provider{Source: env.Allow(m, "*"), Keys: []string{"DB_HOST", "DB_USER"}}
+		got, err := p.Read()
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, got, map[string]any{"DB_HOST": "localhost"})
+	})
+
+	t.Run("not enumerable", func(t *testing.T) {
+		p := env.Provider{Source: env.Allow(m, "*")}
+		_, err := p.Read()
+		assert.Equal[E](t, err.Error(), "env: the source does not implement EnvironSource, Provider.Keys must be set")
+	})
+}
@@ -0,0 +1,133 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// DefaultRegistry is the [Registry] used by modules that do not share one explicitly.
+var DefaultRegistry = new(Registry)
+
+// Registry collects the config structs of independently compiled modules (e.g. plugins),
+// so that all of them can be loaded and documented at once.
+// It is safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	entries []registryEntry
+}
+
+type registryEntry struct {
+	namespace string
+	cfg       reflect.Value
+}
+
+// Register adds cfg to the registry under the given namespace, usually from the init function of a module.
+// The names of the environment variables declared in cfg are prefixed with the namespace,
+// joined with [Options.NameSep] (or "_", if not set), e.g. CACHE_SIZE for the CACHE namespace.
+// An empty namespace adds no prefix.
+// cfg must be a non-nil struct pointer, otherwise Register panics, as well as if the namespace is already registered
+// or a prefixed name (joined with "_") is already used by a config registered under another namespace.
+func (r *Registry) Register(namespace string, cfg any) {
+	pv := reflect.ValueOf(cfg)
+	if !structPtr(pv) {
+		panic("env: cfg must be a non-nil struct pointer")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := registryEntry{namespace: namespace, cfg: pv.Elem()}
+	for _, e := range r.entries {
+		if e.namespace == namespace {
+			panic(fmt.Sprintf("env: namespace `%s` is already registered", namespace))
+		}
+	}
+
+	fields := entry.fields()
+	for _, e := range r.entries {
+		for name, field := range e.fields() {
+			if other, ok := fields[name]; ok {
+				panic(fmt.Sprintf("env: duplicate name `%s` used by fields %s in namespace `%s` and %s in namespace `%s`",
+					name, field, e.namespace, other, namespace))
+			}
+		}
+	}
+
+	r.entries = append(r.entries, entry)
+}
+
+// Load loads all the registered configs in the registration order, see [Load] for details.
+// The names of the required variables that are not set are reported together in a single [NotSetError].
+func (r *Registry) Load(opts *Options) error {
+	opts = setDefaultOptions(opts)
+
+	var notset []string
+	for _, e := range r.snapshot() {
+		err := load(e.cfg, opts, e.prefix(opts))
+		var nse *NotSetError
+		if errors.As(err, &nse) {
+			notset = append(notset, nse.Names...)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(notset) > 0 {
+		return &NotSetError{Names: dedupe(notset), format: opts.Messages.NotSet}
+	}
+
+	return nil
+}
+
+// Usage writes a combined usage message documenting the variables of all the registered configs, see [Usage] for details.
+// The message is written with [Options.UsageFunc], if set, or in the default format.
+func (r *Registry) Usage(w io.Writer, opts *Options) {
+	opts = setDefaultOptions(opts)
+
+	var vars []Var
+	for _, e := range r.snapshot() {
+		prefix := e.prefix(opts)
+		for _, v := range usageVars(e.cfg, opts) {
+			v.Name = prefix + v.Name
+			vars = append(vars, v)
+		}
+	}
+
+	if opts.UsageFunc != nil {
+		opts.UsageFunc(vars, w, opts)
+	} else {
+		defaultUsage(vars, w, opts)
+	}
+}
+
+func (r *Registry) snapshot() []registryEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]registryEntry(nil), r.entries...)
+}
+
+// fields returns the paths of the fields keyed by the prefixed names of their variables, using the default options.
+// The problems with the spec are left for [Registry.Load] to report.
+func (e registryEntry) fields() map[string]string {
+	opts := setDefaultOptions(nil)
+	prefix := e.prefix(opts)
+	fields := make(map[string]string)
+	for _, v := range parseSpec(e.cfg, opts, func(string) {}) {
+		if !v.dynamic && !v.remain {
+			fields[prefix+v.Name] = v.Field
+		}
+	}
+	return fields
+}
+
+func (e registryEntry) prefix(opts *Options) string {
+	if e.namespace == "" {
+		return ""
+	}
+	return e.namespace + autoSep(opts)
+}
@@ -0,0 +1,66 @@
+package env_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestRegistry(t *testing.T) {
+	var cache struct {
+		Size int `env:"SIZE" default:"100"`
+	}
+	var queue struct {
+		URL     string `env:"URL,required"`
+		Workers int    `env:"WORKERS,required"`
+	}
+
+	var r env.Registry
+	r.Register("CACHE", &cache)
+	r.Register("QUEUE", &queue)
+
+	register := func() { r.Register("CACHE", &cache) }
+	assert.Panics[E](t, register, "env: namespace `CACHE` is already registered")
+
+	t.Run("load", func(t *testing.T) {
+		m := env.Map{"CACHE_SIZE": "10", "QUEUE_URL": "amqp://"}
+
+		err := r.Load(&env.Options{Source: m})
+		var notSetErr *env.NotSetError
+		assert.AsErr[F](t, err, &notSetErr)
+		assert.Equal[E](t, notSetErr.Names, []string{"QUEUE_WORKERS"})
+
+		m["QUEUE_WORKERS"] = "4"
+		err = r.Load(&env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cache.Size, 10)
+		assert.Equal[E](t, queue.URL, "amqp://")
+		assert.Equal[E](t, queue.Workers, 4)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		var buf bytes.Buffer
+		r.Usage(&buf, nil)
+		assert.Equal[E](t, buf.String(), `  CACHE_SIZE     int     default 100
+  QUEUE_URL      string  required
+  QUEUE_WORKERS  int     required
+`)
+	})
+}
+
+func TestRegistry_duplicateNames(t *testing.T) {
+	var a struct {
+		BC int `env:"B_C"`
+	}
+	var ab struct {
+		C int `env:"C"`
+	}
+
+	var r env.Registry
+	r.Register("A", &a)
+	register := func() { r.Register("A_B", &ab) }
+	assert.Panics[E](t, register, "env: duplicate name `A_B_C` used by fields BC in namespace `A` and C in namespace `A_B`")
+}
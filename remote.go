@@ -0,0 +1,132 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPSource is a [Source] that fetches the variables from a remote document over HTTP(S).
+// See [HTTP] for details.
+type HTTPSource struct {
+	client *http.Client
+	url    string
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	m       Map
+	etag    string
+	fetched time.Time
+}
+
+// HTTP returns an [HTTPSource] that fetches the document at the given URL, e.g. from a centralized config service.
+// The document is either a JSON object with string values (if served as application/json) or in the .env syntax (see [File] for the format).
+// The variables are cached for ttl; after that, the document is revalidated using the ETag header, if the server provides it.
+// If client is nil, [http.DefaultClient] is used.
+// To let local values override the remote ones, combine the sources with [MultiSource], e.g. MultiSource(OS, src).
+func HTTP(client *http.Client, url string, ttl time.Duration) *HTTPSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPSource{client: client, url: url, ttl: ttl}
+}
+
+// LookupEnv implements the [Source] interface.
+func (s *HTTPSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+// If fetching fails, the error is returned and the next lookup tries again.
+func (s *HTTPSource) LookupEnvErr(key string) (string, bool, error) {
+	return s.LookupEnvContext(context.Background(), key)
+}
+
+// LookupEnvContext implements the [ContextSource] interface.
+// The context is used for the request if the document needs to be fetched.
+func (s *HTTPSource) LookupEnvContext(ctx context.Context, key string) (string, bool, error) {
+	m, err := s.snapshot(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := m[key]
+	return value, ok, nil
+}
+
+// Environ implements the [EnvironSource] interface.
+// If fetching fails, it returns nil.
+func (s *HTTPSource) Environ() []string {
+	m, err := s.snapshot(context.Background())
+	if err != nil {
+		return nil
+	}
+	return m.Environ()
+}
+
+func (s *HTTPSource) snapshot(ctx context.Context) (Map, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m != nil && time.Since(s.fetched) < s.ttl {
+		return s.m, nil
+	}
+	if err := s.fetch(ctx); err != nil {
+		return nil, fmt.Errorf("env: fetching %s: %w", s.url, err)
+	}
+	return s.m, nil
+}
+
+func (s *HTTPSource) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	if s.m != nil && s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if s.m != nil {
+			s.fetched = time.Now()
+			return nil
+		}
+		fallthrough
+	default:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var m Map
+	if typ, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); typ == "application/json" {
+		if err := json.Unmarshal(body, &m); err != nil {
+			return fmt.Errorf("decoding json: %w", err)
+		}
+		if m == nil {
+			m = Map{}
+		}
+	} else if m, err = parseDotenv(string(body)); err != nil {
+		return err
+	}
+
+	s.m = m
+	s.etag = resp.Header.Get("ETag")
+	s.fetched = time.Now()
+	return nil
+}
@@ -0,0 +1,91 @@
+package env_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestHTTP(t *testing.T) {
+	var requests, notModified int
+	body := `{"PORT":"8080"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		etag := `"` + body + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	var cfg struct {
+		Port int `env:"PORT"`
+	}
+
+	t.Run("ttl", func(t *testing.T) {
+		src := env.HTTP(srv.Client(), srv.URL, time.Hour)
+		for i := 0; i < 2; i++ {
+			err := env.Load(&cfg, &env.Options{Source: src})
+			assert.NoErr[F](t, err)
+			assert.Equal[E](t, cfg.Port, 8080)
+		}
+		assert.Equal[E](t, requests, 1)
+	})
+
+	t.Run("etag", func(t *testing.T) {
+		requests = 0
+		src := env.HTTP(srv.Client(), srv.URL, 0)
+		err := env.Load(&cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		err = env.Load(&cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, requests, 2)
+		assert.Equal[E](t, notModified, 1)
+
+		body = `{"PORT":"9090"}`
+		err = env.Load(&cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Port, 9090)
+	})
+
+	t.Run("local override", func(t *testing.T) {
+		src := env.MultiSource(env.Map{"PORT": "1234"}, env.HTTP(srv.Client(), srv.URL, time.Hour))
+		err := env.Load(&cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Port, 1234)
+	})
+
+	t.Run("dotenv", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("export PORT=7070 # comment\nNAME='my app'\n"))
+		}))
+		defer srv.Close()
+
+		src := env.HTTP(srv.Client(), srv.URL, time.Hour)
+		value, ok := src.LookupEnv("NAME")
+		assert.Equal[E](t, ok, true)
+		assert.Equal[E](t, value, "my app")
+		err := env.Load(&cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Port, 7070)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		defer srv.Close()
+
+		src := env.HTTP(srv.Client(), srv.URL, time.Hour)
+		err := env.Load(&cfg, &env.Options{Source: src})
+		assert.Equal[E](t, err.Error(), "env: fetching "+srv.URL+": unexpected status 404 Not Found")
+	})
+}
@@ -0,0 +1,96 @@
+package env
+
+import "reflect"
+
+// Resolution explains how a single environment variable is resolved, see [Resolve].
+type Resolution struct {
+	Name        string  // The name of the variable.
+	Var         *Var    // The variable declared in the struct, or nil if there is none.
+	Layers      []Layer // The result of the lookup in each layer of [Options.Source], in order of precedence.
+	Winner      int     // The index of the layer the value is taken from, or -1 if no layer has the variable.
+	Value       string  // The resolved value, before expansion.
+	UsesDefault bool    // True, if no layer has the variable and the default value applies.
+}
+
+// Layer is the result of looking up a variable in a single source.
+type Layer struct {
+	Source Source // The source of the layer.
+	Value  string // The value of the variable, if found.
+	Found  bool   // True, if the source has the variable.
+	Err    error  // The lookup error, if any.
+}
+
+// Resolve explains how the variable with the given name is resolved when loading the given struct.
+// If [Options.Source] is a [MultiSource], each of its layers is reported separately, which helps debugging precedence issues.
+// The variable is looked up the same way as by [Load], i.e. in the source from its `source` tag and with [Options.Suffix].
+// cfg must be a non-nil struct pointer, otherwise Resolve panics.
+func Resolve(cfg any, name string, opts *Options) Resolution {
+	pv := reflect.ValueOf(cfg)
+	if !structPtr(pv) {
+		panic("env: cfg must be a non-nil struct pointer")
+	}
+
+	opts = setDefaultOptions(opts)
+
+	res := Resolution{Name: name, Winner: -1}
+	for _, v := range parseVars(pv.Elem(), opts) {
+		if v.Name == name {
+			v := v
+			res.Var = &v
+			break
+		}
+	}
+
+	ropts := opts
+	if res.Var != nil {
+		ropts = routeOpts(opts, *res.Var)
+	}
+	for i, src := range layers(ropts.Source) {
+		lopts := *ropts
+		lopts.Source = src
+		value, ok, err := lookupEnv(&lopts, name, false)
+		res.Layers = append(res.Layers, Layer{Source: src, Value: value, Found: ok, Err: err})
+		if ok && res.Winner == -1 {
+			res.Winner = i
+			res.Value = value
+		}
+	}
+
+	if res.Winner == -1 && res.Var != nil && res.Var.hasDefaultTag {
+		res.UsesDefault = true
+		res.Value = res.Var.Default
+	}
+
+	return res
+}
+
+// Origin describes where the value of a variable loaded by [Load] comes from, see [Options.Provenance].
+type Origin struct {
+	Source  Source // The layer of [Options.Source] the value is taken from, or nil if the default value is used.
+	Default bool   // True, if no layer has the variable and the default value is used.
+}
+
+// layers returns the layers of the source: the sources of a [MultiSource], or the source itself.
+func layers(src Source) []Source {
+	if ms, ok := src.(multiSource); ok {
+		return ms
+	}
+	return []Source{src}
+}
+
+// originOf returns the origin of the variable that is known to be set.
+// The suffixed key is checked first, see [Options.Suffix].
+func originOf(opts *Options, name string) Origin {
+	keys := []string{name}
+	if opts.Suffix != "" {
+		keys = []string{name + autoSep(opts) + opts.Suffix, name}
+	}
+	for _, key := range keys {
+		for _, src := range layers(opts.Source) {
+			if _, ok, _ := lookup(src, key); ok {
+				return Origin{Source: src}
+			}
+		}
+	}
+	return Origin{Source: opts.Source}
+}
@@ -0,0 +1,89 @@
+package env_test
+
+import (
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestResolve(t *testing.T) {
+	local := env.Map{"PORT": "8080"}
+	file := env.Map{"PORT": "80", "HOST": "localhost"}
+	opts := &env.Options{Source: env.MultiSource(local, file)}
+
+	var cfg struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+		Mode string `env:"MODE" default:"dev"`
+	}
+
+	t.Run("overridden", func(t *testing.T) {
+		res := env.Resolve(&cfg, "PORT", opts)
+		assert.Equal[E](t, res.Winner, 0)
+		assert.Equal[E](t, res.Value, "8080")
+		assert.Equal[E](t, len(res.Layers), 2)
+		assert.Equal[E](t, res.Layers[1].Found, true)
+		assert.Equal[E](t, res.Layers[1].Value, "80")
+	})
+
+	t.Run("lower layer", func(t *testing.T) {
+		res := env.Resolve(&cfg, "HOST", opts)
+		assert.Equal[E](t, res.Winner, 1)
+		assert.Equal[E](t, res.Value, "localhost")
+	})
+
+	t.Run("default", func(t *testing.T) {
+		res := env.Resolve(&cfg, "MODE", opts)
+		assert.Equal[E](t, res.Winner, -1)
+		assert.Equal[E](t, res.UsesDefault, true)
+		assert.Equal[E](t, res.Value, "dev")
+	})
+
+	t.Run("routing and suffix", func(t *testing.T) {
+		var cfg struct {
+			Host     string `env:"DB_HOST"`
+			Password string `env:"DB_PASSWORD" source:"vault"`
+		}
+		opts := &env.Options{
+			Source:  env.Map{"DB_HOST_EU": "db"},
+			Sources: map[string]env.Source{"vault": env.Map{"DB_PASSWORD": "secret"}},
+			Suffix:  "EU",
+		}
+
+		res := env.Resolve(&cfg, "DB_HOST", opts)
+		assert.Equal[E](t, res.Winner, 0)
+		assert.Equal[E](t, res.Value, "db")
+
+		res = env.Resolve(&cfg, "DB_PASSWORD", opts)
+		assert.Equal[E](t, res.Winner, 0)
+		assert.Equal[E](t, res.Value, "secret")
+	})
+
+	t.Run("not declared", func(t *testing.T) {
+		res := env.Resolve(&cfg, "FOO", opts)
+		assert.Equal[E](t, res.Var == nil, true)
+		assert.Equal[E](t, res.Winner, -1)
+	})
+}
+
+func TestProvenance(t *testing.T) {
+	osEnv := env.Map{"HOST": "prod.example.com"}
+	dotenv := env.Map{"HOST": "localhost", "PORT": "8080"}
+
+	var cfg struct {
+		Host    string `env:"HOST"`
+		Port    int    `env:"PORT"`
+		Timeout int    `env:"TIMEOUT" default:"30"`
+		Debug   bool   `env:"DEBUG"`
+	}
+	provenance := make(map[string]env.Origin)
+	err := env.Load(&cfg, &env.Options{Source: env.MultiSource(osEnv, dotenv), Provenance: provenance})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, provenance, map[string]env.Origin{
+		"HOST":    {Source: osEnv},
+		"PORT":    {Source: dotenv},
+		"TIMEOUT": {Default: true},
+	})
+}
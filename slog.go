@@ -0,0 +1,34 @@
+//go:build go1.21
+
+package env
+
+import "log/slog"
+
+// LogValue implements the [slog.LogValuer] interface,
+// so that log pipelines can index the names of the variables that are not set.
+func (e *NotSetError) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("msg", e.Error()),
+		slog.Any("names", e.Names),
+	)
+}
+
+// LogValue implements the [slog.LogValuer] interface,
+// so that log pipelines can index the variable and the struct field that failed to parse.
+func (e *ParseError) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("msg", e.Error()),
+		slog.String("name", e.Name),
+		slog.String("field", e.Field),
+	)
+}
+
+// LogValue implements the [slog.LogValuer] interface,
+// so that log pipelines can index the variable and the struct field that failed validation.
+func (e *ValidationError) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("msg", e.Error()),
+		slog.String("name", e.Name),
+		slog.String("field", e.Field),
+	)
+}
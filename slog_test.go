@@ -0,0 +1,47 @@
+//go:build go1.21
+
+package env_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	t.Run("not set", func(t *testing.T) {
+		buf.Reset()
+		err := &env.NotSetError{Names: []string{"FOO", "BAR"}}
+		logger.Error("loading config", "err", err)
+		assert.Equal[E](t, buf.String(), `level=ERROR msg="loading config" err.msg="env: FOO BAR are required but not set" err.names="[FOO BAR]"`+"\n")
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		var cfg struct {
+			DB struct {
+				Port int `env:"PORT"`
+			} `env:"DB"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: env.Map{"DB_PORT": "x"}, NameSep: "_"})
+		var parseErr *env.ParseError
+		assert.AsErr[F](t, err, &parseErr)
+
+		buf.Reset()
+		logger.Error("loading config", "err", err)
+		assert.Equal[E](t, buf.String(), `level=ERROR msg="loading config" err.msg="parsing int: strconv.ParseInt: parsing \"x\": invalid syntax" err.name=DB_PORT err.field=DB.Port`+"\n")
+	})
+}
@@ -1,6 +1,27 @@
 package env
 
-import "os"
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
 
 // Source represents a source of environment variables.
 type Source interface {
@@ -8,8 +29,48 @@ type Source interface {
 	LookupEnv(key string) (value string, ok bool)
 }
 
+// FallibleSource is a [Source] whose lookups may fail, e.g. because of a network error.
+// If the source passed to [Load] implements it, LookupEnvErr is used instead of LookupEnv,
+// and the first error returned aborts loading.
+type FallibleSource interface {
+	Source
+	LookupEnvErr(key string) (value string, ok bool, err error)
+}
+
+// ContextSource is a [Source] whose lookups can be canceled, e.g. because they make network calls.
+// If the source passed to [LoadContext] implements it, LookupEnvContext is used with the given context;
+// otherwise, a lookup is abandoned (but not canceled) once the context is done.
+type ContextSource interface {
+	Source
+	LookupEnvContext(ctx context.Context, key string) (value string, ok bool, err error)
+}
+
+// EnvironSource is a [Source] that can enumerate its variables.
+type EnvironSource interface {
+	Source
+	// Environ returns all variables in the KEY=VALUE form, see [os.Environ].
+	Environ() []string
+}
+
+// BatchSource is a [Source] that can look up multiple variables in a single round trip.
+// [Load] uses it to resolve all the variables of a struct at once, which speeds up the startup with remote sources.
+type BatchSource interface {
+	Source
+	// LookupEnvs returns the values of the given keys that are set.
+	LookupEnvs(keys []string) map[string]string
+}
+
 // OS is the main [Source] that uses [os.LookupEnv].
-var OS Source = sourceFunc(os.LookupEnv)
+// It implements [EnvironSource] using [os.Environ].
+var OS Source = osSource{}
+
+type osSource struct{}
+
+// LookupEnv implements the [Source] interface.
+func (osSource) LookupEnv(key string) (string, bool) { return os.LookupEnv(key) }
+
+// Environ implements the [EnvironSource] interface.
+func (osSource) Environ() []string { return os.Environ() }
 
 // Map is a [Source] implementation useful in tests.
 type Map map[string]string
@@ -20,6 +81,731 @@ func (m Map) LookupEnv(key string) (string, bool) {
 	return value, ok
 }
 
-type sourceFunc func(key string) (string, bool)
+// Environ implements the [EnvironSource] interface.
+func (m Map) Environ() []string {
+	environ := make([]string, 0, len(m))
+	for key, value := range m {
+		environ = append(environ, key+"="+value)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// Slice returns a [Map] with the variables from a slice of KEY=VALUE strings,
+// the format of [os.Environ] and [exec.Cmd.Env], e.g. to load a config from a captured child process environment
+// or from overrides provided on the command line. If a key is duplicated, the last value wins.
+// The entries without = are ignored.
+func Slice(environ []string) Map {
+	m := make(Map, len(environ))
+	for _, kv := range environ {
+		if kv == "" {
+			continue
+		}
+		// on Windows, os.Environ may contain entries like =C:=C:\, whose keys start with =.
+		if i := strings.Index(kv[1:], "=") + 1; i > 0 {
+			m[kv[:i]] = kv[i+1:]
+		}
+	}
+	return m
+}
+
+// LookupFunc is an adapter to use a function as a [FallibleSource], e.g. to look up each key in Redis:
+//
+//	src := env.LookupFunc(func(key string) (string, bool, error) {
+//		value, err := rdb.Get(ctx, key).Result()
+//		if errors.Is(err, redis.Nil) {
+//			return "", false, nil
+//		}
+//		return value, err == nil, err
+//	})
+type LookupFunc func(key string) (value string, ok bool, err error)
+
+// LookupEnv implements the [Source] interface.
+func (fn LookupFunc) LookupEnv(key string) (string, bool) {
+	value, ok, _ := fn(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+func (fn LookupFunc) LookupEnvErr(key string) (string, bool, error) { return fn(key) }
+
+// CachedSource is a [Source] that fetches all variables at once and caches them until invalidated.
+// See [Cached] for details.
+type CachedSource struct {
+	fetch func() (Map, error)
+
+	mu sync.Mutex
+	m  Map
+}
+
+// Cached returns a [CachedSource] that calls fetch on the first lookup after creation or invalidation.
+// It is useful for remote key/value stores, e.g. a Redis hash:
+//
+//	src := env.Cached(func() (env.Map, error) {
+//		return rdb.HGetAll(ctx, "config").Result()
+//	})
+//
+// To pick up changes, call [CachedSource.Invalidate], e.g. on Redis keyspace notifications.
+func Cached(fetch func() (Map, error)) *CachedSource {
+	return &CachedSource{fetch: fetch}
+}
+
+// LookupEnv implements the [Source] interface.
+func (s *CachedSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+// If fetching fails, the error is returned and the next lookup tries again.
+func (s *CachedSource) LookupEnvErr(key string) (string, bool, error) {
+	m, err := s.snapshot()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := m[key]
+	return value, ok, nil
+}
+
+// Environ implements the [EnvironSource] interface.
+// If fetching fails, it returns nil.
+func (s *CachedSource) Environ() []string {
+	m, err := s.snapshot()
+	if err != nil {
+		return nil
+	}
+	return m.Environ()
+}
+
+// Invalidate drops the cached variables, so that the next lookup fetches them again.
+func (s *CachedSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m = nil
+}
+
+func (s *CachedSource) snapshot() (Map, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		m, err := s.fetch()
+		if err != nil {
+			return nil, fmt.Errorf("env: fetching variables: %w", err)
+		}
+		if m == nil {
+			m = Map{}
+		}
+		s.m = m
+	}
+	return s.m, nil
+}
+
+// Exec runs the given command and returns a [Map] with the KEY=VALUE pairs parsed from its output.
+// It allows using existing secret CLIs (e.g. `op`, `vault`, `sops`) as a [Source] without temporary files.
+// Empty lines and lines starting with # are ignored, as well as the optional `export` prefix.
+func Exec(cmd *exec.Cmd) (Map, error) {
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("env: running %s: %w", cmd.Path, err)
+	}
+	return parsePairs(bytes.NewReader(out))
+}
+
+// SQL runs the given query and returns a [Map] with the KEY/VALUE rows it returns,
+// e.g. SELECT name, value FROM settings WHERE app = $1.
+// The query must return exactly two columns, which are scanned as strings.
+func SQL(ctx context.Context, db *sql.DB, query string, args ...any) (Map, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("env: querying settings: %w", err)
+	}
+	defer rows.Close()
+
+	m := make(Map)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("env: scanning settings: %w", err)
+		}
+		m[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("env: reading settings: %w", err)
+	}
+
+	return m, nil
+}
+
+// Read returns a [Map] with the KEY=VALUE pairs read from r (see [Exec] for the format),
+// e.g. env.Read(os.Stdin) allows wrappers to pipe an environment into a child process
+// without touching the real process environment or temporary files.
+func Read(r io.Reader) (Map, error) {
+	return parsePairs(r)
+}
+
+// ReadProfile is like [Read], but supports [name] sections, so that a single file can hold multiple profiles:
+//
+//	LOG_LEVEL=info
+//
+//	[dev]
+//	LOG_LEVEL=debug
+//
+//	[prod]
+//	DB_HOST=db.internal
+//
+// The pairs before the first section are shared; the pairs in the section of the given profile override them.
+// The other sections are ignored.
+func ReadProfile(r io.Reader, profile string) (Map, error) {
+	shared, active := make(Map), make(Map)
+	err := scanPairs(r, true, func(section, key, value string) {
+		switch section {
+		case "":
+			shared[key] = value
+		case profile:
+			active[key] = value
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range active {
+		shared[key] = value
+	}
+	return shared, nil
+}
+
+// SourceOpener constructs a [Source] from the URL given to [Bootstrap].
+type SourceOpener func(u *url.URL) (Source, error)
+
+// Bootstrap looks up the variable with the given name in src, e.g. CONFIG_URL=file:///etc/app.env,
+// and returns the [Source] constructed by the opener registered for the scheme of the URL,
+// so that deployments only have to set a single variable.
+// The file scheme is supported out of the box (see [File] for the format) and can be overridden;
+// other schemes, e.g. ssm, require an opener.
+// If the variable is not set, a [NotSetError] is returned.
+func Bootstrap(src Source, name string, openers map[string]SourceOpener) (Source, error) {
+	value, ok, err := lookup(src, name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &NotSetError{Names: []string{name}}
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("env: %s: parsing url: %w", name, err)
+	}
+
+	open, ok := openers[u.Scheme]
+	if !ok && u.Scheme == "file" {
+		open, ok = openFile, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("env: %s: no opener for scheme %q", name, u.Scheme)
+	}
+
+	s, err := open(u)
+	if err != nil {
+		return nil, fmt.Errorf("env: %s: opening source: %w", name, err)
+	}
+	return s, nil
+}
+
+// openFile opens the file:///path and file:path URLs for [Bootstrap].
+func openFile(u *url.URL) (Source, error) {
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+	data, err := os.ReadFile(filepath.FromSlash(path))
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseDotenv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// IgnoreMissing returns an empty [Map] instead of the error if err reports a missing file (see [fs.ErrNotExist]).
+// It wraps the constructors of file-based sources for the files that are optional by convention, e.g. .env.local:
+//
+//	local, err := env.IgnoreMissing(env.File(".env.local"))
+func IgnoreMissing(m Map, err error) (Map, error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		return Map{}, nil
+	}
+	return m, err
+}
+
+// ErrVerification is returned by [VerifiedFile] when the contents of a file cannot be trusted.
+var ErrVerification = errors.New("env: verification failed")
+
+// VerifiedFile reads the file at path, verifies its contents with the given function,
+// and returns a [Map] with the KEY=VALUE pairs parsed from it (see [File] for the format).
+// Nothing is parsed if the verification fails.
+// It is useful when config files are distributed through less-trusted channels,
+// see [VerifyEd25519] and [VerifySHA256].
+func VerifiedFile(path string, verify func(data []byte) error) (Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("env: reading file: %w", err)
+	}
+	if err := verify(data); err != nil {
+		return nil, fmt.Errorf("env: %s: %w", path, err)
+	}
+	m, err := parseDotenv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("env: %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// VerifyEd25519 returns a function for [VerifiedFile] that checks the detached Ed25519 signature of the data.
+func VerifyEd25519(pub ed25519.PublicKey, sig []byte) func(data []byte) error {
+	return func(data []byte) error {
+		if !ed25519.Verify(pub, data, sig) {
+			return fmt.Errorf("%w: invalid signature", ErrVerification)
+		}
+		return nil
+	}
+}
+
+// VerifySHA256 returns a function for [VerifiedFile] that compares the SHA-256 checksum of the data
+// with the given hex-encoded one.
+func VerifySHA256(sum string) func(data []byte) error {
+	return func(data []byte) error {
+		got := sha256.Sum256(data)
+		if hex.EncodeToString(got[:]) != strings.ToLower(sum) {
+			return fmt.Errorf("%w: checksum mismatch", ErrVerification)
+		}
+		return nil
+	}
+}
+
+// parsePairs parses KEY=VALUE pairs from r, one pair per line.
+func parsePairs(r io.Reader) (Map, error) {
+	m := make(Map)
+	if err := scanPairs(r, false, func(_, key, value string) { m[key] = value }); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// scanPairs calls fn for each KEY=VALUE pair in r along with the name of its [section], if sections are allowed.
+func scanPairs(r io.Reader, sections bool, fn func(section, key, value string)) error {
+	var section string
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if sections && strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("env: line %d: invalid KEY=VALUE pair", n)
+		}
+		fn(section, strings.TrimSpace(key), value)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("env: reading pairs: %w", err)
+	}
+	return nil
+}
+
+// ErrTimeout is returned by a [Timeout] source when a lookup takes too long.
+var ErrTimeout = errors.New("env: lookup timed out")
+
+// TimeoutPolicy defines how a [Timeout] source reports a timed out lookup.
+type TimeoutPolicy int
+
+const (
+	FailClosed TimeoutPolicy = iota // The lookup fails with [ErrTimeout], which aborts [Load].
+	FailOpen                        // The variable is treated as unset.
+)
+
+// Timeout returns a [FallibleSource] that bounds each lookup in src by the given duration.
+// The policy defines what happens when the duration is exceeded.
+// Note that a timed out lookup is abandoned but not canceled, since [Source] does not support cancellation.
+func Timeout(src Source, d time.Duration, policy TimeoutPolicy) FallibleSource {
+	return &timeoutSource{src: src, timeout: d, policy: policy}
+}
+
+type timeoutSource struct {
+	src     Source
+	timeout time.Duration
+	policy  TimeoutPolicy
+}
+
+type lookupResult struct {
+	value string
+	ok    bool
+	err   error
+}
+
+// LookupEnv implements the [Source] interface.
+func (s *timeoutSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+func (s *timeoutSource) LookupEnvErr(key string) (string, bool, error) {
+	ch := make(chan lookupResult, 1)
+	go func() {
+		value, ok, err := lookup(s.src, key)
+		ch <- lookupResult{value, ok, err}
+	}()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-ch:
+		return r.value, r.ok, r.err
+	case <-timer.C:
+		if s.policy == FailOpen {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("%w: %s", ErrTimeout, key)
+	}
+}
 
-func (fn sourceFunc) LookupEnv(key string) (string, bool) { return fn(key) }
+// Retry returns a [FallibleSource] that retries failed lookups in src up to the given number of attempts.
+// The delay between attempts starts at backoff and doubles after each attempt.
+// If all attempts fail, the last error is returned wrapped.
+// Only errors returned by a [FallibleSource] are retried; a variable that is not set is not an error.
+func Retry(src Source, attempts int, backoff time.Duration) FallibleSource {
+	if attempts < 1 {
+		panic("env: attempts must be positive")
+	}
+	return &retrySource{src: src, attempts: attempts, backoff: backoff}
+}
+
+type retrySource struct {
+	src      Source
+	attempts int
+	backoff  time.Duration
+}
+
+// LookupEnv implements the [Source] interface.
+func (s *retrySource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+func (s *retrySource) LookupEnvErr(key string) (string, bool, error) {
+	var err error
+	delay := s.backoff
+	for i := 0; i < s.attempts; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		var value string
+		var ok bool
+		if value, ok, err = lookup(s.src, key); err == nil {
+			return value, ok, nil
+		}
+	}
+	return "", false, fmt.Errorf("env: looking up %s: %d attempts failed: %w", key, s.attempts, err)
+}
+
+// Prefixes returns a [Source] that looks up PREFIX+KEY in src for each of the given prefixes in order,
+// returning the first one found. It allows shared platform defaults to be overridden per service,
+// e.g. Prefixes(OS, "BILLING_", "GLOBAL_") looks up BILLING_DB_HOST and then GLOBAL_DB_HOST for DB_HOST.
+// Use an empty prefix to look up the key as is.
+func Prefixes(src Source, prefixes ...string) FallibleSource {
+	return &prefixSource{src: src, prefixes: prefixes}
+}
+
+type prefixSource struct {
+	src      Source
+	prefixes []string
+}
+
+// LookupEnv implements the [Source] interface.
+func (s *prefixSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+func (s *prefixSource) LookupEnvErr(key string) (string, bool, error) {
+	for _, prefix := range s.prefixes {
+		value, ok, err := lookup(s.src, prefix+key)
+		if err != nil || ok {
+			return value, ok, err
+		}
+	}
+	return "", false, nil
+}
+
+// Trace returns a [Source] that calls fn after each lookup in src with the key, whether it was found, the elapsed time, and the error.
+// Unlike [Hooks.Lookup], it can wrap a single layer of a [MultiSource], e.g. to find out why a value came from the wrong layer:
+//
+//	src := env.MultiSource(env.OS, env.Trace(dotenv, func(key string, found bool, _ time.Duration, _ error) {
+//		log.Printf(".env: %s found=%t", key, found)
+//	}))
+func Trace(src Source, fn func(key string, found bool, elapsed time.Duration, err error)) FallibleSource {
+	return &traceSource{src: src, fn: fn}
+}
+
+type traceSource struct {
+	src Source
+	fn  func(string, bool, time.Duration, error)
+}
+
+// LookupEnv implements the [Source] interface.
+func (s *traceSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+func (s *traceSource) LookupEnvErr(key string) (string, bool, error) {
+	start := time.Now()
+	value, ok, err := lookup(s.src, key)
+	s.fn(key, ok, time.Since(start), err)
+	return value, ok, err
+}
+
+// CaseInsensitive returns a [Source] that matches the keys regardless of case,
+// e.g. for .env files that use lower-case keys or environments migrated from Windows.
+// The exact key is looked up first; then, if src implements [EnvironSource], its variables are searched
+// for a case-insensitive match, otherwise the lower- and upper-cased keys are looked up.
+func CaseInsensitive(src Source) FallibleSource {
+	return &caseInsensitiveSource{src: src}
+}
+
+type caseInsensitiveSource struct {
+	src Source
+}
+
+// LookupEnv implements the [Source] interface.
+func (s *caseInsensitiveSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+func (s *caseInsensitiveSource) LookupEnvErr(key string) (string, bool, error) {
+	if value, ok, err := lookup(s.src, key); err != nil || ok {
+		return value, ok, err
+	}
+	if es, ok := s.src.(EnvironSource); ok {
+		for _, kv := range es.Environ() {
+			if k, v, _ := strings.Cut(kv, "="); strings.EqualFold(k, key) {
+				return v, true, nil
+			}
+		}
+		return "", false, nil
+	}
+	for _, k := range []string{strings.ToLower(key), strings.ToUpper(key)} {
+		if k == key {
+			continue
+		}
+		if value, ok, err := lookup(s.src, k); err != nil || ok {
+			return value, ok, err
+		}
+	}
+	return "", false, nil
+}
+
+// MapKeys returns a [Source] that transforms the requested keys with fn before looking them up in src.
+// It bridges naming mismatches between the struct and an external source,
+// e.g. MapKeys(src, strings.ToLower) for a source with lower-cased keys.
+func MapKeys(src Source, fn func(key string) string) FallibleSource {
+	return &mapKeysSource{src: src, fn: fn}
+}
+
+type mapKeysSource struct {
+	src Source
+	fn  func(string) string
+}
+
+// LookupEnv implements the [Source] interface.
+func (s *mapKeysSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+func (s *mapKeysSource) LookupEnvErr(key string) (string, bool, error) {
+	return lookup(s.src, s.fn(key))
+}
+
+// Allow returns a [Source] that only answers lookups of keys matching at least one of the given patterns.
+// It can be used to prevent a config struct from reading unrelated variables, e.g. secrets.
+// The patterns use the [path.Match] syntax, e.g. "APP_*". An invalid pattern causes a panic.
+func Allow(src Source, patterns ...string) FallibleSource {
+	checkPatterns(patterns)
+	return &filterSource{src: src, keep: func(key string) bool { return matchAny(patterns, key) }}
+}
+
+// Deny returns a [Source] that hides the keys matching any of the given patterns.
+// The patterns use the [path.Match] syntax, e.g. "*_SECRET". An invalid pattern causes a panic.
+func Deny(src Source, patterns ...string) FallibleSource {
+	checkPatterns(patterns)
+	return &filterSource{src: src, keep: func(key string) bool { return !matchAny(patterns, key) }}
+}
+
+type filterSource struct {
+	src  Source
+	keep func(key string) bool
+}
+
+// LookupEnv implements the [Source] interface.
+func (s *filterSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+func (s *filterSource) LookupEnvErr(key string) (string, bool, error) {
+	if !s.keep(key) {
+		return "", false, nil
+	}
+	return lookup(s.src, key)
+}
+
+func checkPatterns(patterns []string) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			panic(fmt.Sprintf("env: invalid pattern `%s`", pattern))
+		}
+	}
+}
+
+func matchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MultiSource returns a [Source] that combines the given sources into layers.
+// A variable is looked up in each source in order, and the first one that has it wins,
+// e.g. MultiSource(OS, file) allows overriding the values from a file with the OS environment.
+// See [Resolve] for debugging the precedence of layers.
+func MultiSource(srcs ...Source) FallibleSource {
+	return multiSource(srcs)
+}
+
+type multiSource []Source
+
+// LookupEnv implements the [Source] interface.
+func (ms multiSource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := ms.LookupEnvErr(key)
+	return value, ok
+}
+
+// LookupEnvErr implements the [FallibleSource] interface.
+func (ms multiSource) LookupEnvErr(key string) (string, bool, error) {
+	for _, src := range ms {
+		value, ok, err := lookup(src, key)
+		if err != nil || ok {
+			return value, ok, err
+		}
+	}
+	return "", false, nil
+}
+
+// Environ implements the [EnvironSource] interface.
+// Only the sources that implement [EnvironSource] are enumerated.
+func (ms multiSource) Environ() []string {
+	seen := make(map[string]struct{})
+	var environ []string
+	for _, src := range ms {
+		es, ok := src.(EnvironSource)
+		if !ok {
+			continue
+		}
+		for _, kv := range es.Environ() {
+			key, _, _ := strings.Cut(kv, "=")
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				environ = append(environ, kv)
+			}
+		}
+	}
+	return environ
+}
+
+// Select returns one of the sources chosen by the value of the key variable in selector, e.g. APP_ENV.
+// If the variable is not set, the source with the empty name is used, if present.
+// It allows configuring the sources for each environment in one place:
+//
+//	src, err := env.Select(env.OS, "APP_ENV", map[string]env.Source{
+//		"dev":  devSource,
+//		"prod": prodSource,
+//	})
+func Select(selector Source, key string, sources map[string]Source) (Source, error) {
+	name, _, err := lookup(selector, key)
+	if err != nil {
+		return nil, err
+	}
+	src, ok := sources[name]
+	if !ok {
+		return nil, fmt.Errorf("env: no source for %s=%q", key, name)
+	}
+	return src, nil
+}
+
+// environKeys returns the names of all variables in src, if it implements [EnvironSource].
+func environKeys(src Source) ([]string, bool) {
+	es, ok := src.(EnvironSource)
+	if !ok {
+		return nil, false
+	}
+	environ := es.Environ()
+	keys := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		key, _, _ := strings.Cut(kv, "=")
+		keys = append(keys, key)
+	}
+	return keys, true
+}
+
+// lookup retrieves the value of the environment variable from src,
+// using [FallibleSource] if implemented.
+func lookup(src Source, key string) (string, bool, error) {
+	if fs, ok := src.(FallibleSource); ok {
+		return fs.LookupEnvErr(key)
+	}
+	value, ok := src.LookupEnv(key)
+	return value, ok, nil
+}
+
+// lookupContext is like lookup, but returns early with the context's error once ctx is done.
+func lookupContext(ctx context.Context, src Source, key string) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, fmt.Errorf("env: %s: %w", key, err)
+	}
+	if cs, ok := src.(ContextSource); ok {
+		return cs.LookupEnvContext(ctx, key)
+	}
+
+	ch := make(chan lookupResult, 1)
+	go func() {
+		value, ok, err := lookup(src, key)
+		ch <- lookupResult{value, ok, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.value, r.ok, r.err
+	case <-ctx.Done():
+		return "", false, fmt.Errorf("env: %s: %w", key, ctx.Err())
+	}
+}
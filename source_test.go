@@ -1,7 +1,22 @@
 package env_test
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"go-simpler.org/env"
 	"go-simpler.org/env/internal/assert"
@@ -26,3 +41,531 @@ func TestMap_LookupEnv(t *testing.T) {
 	assert.Equal[E](t, cfg.Bar, 2)
 	assert.Equal[E](t, cfg.Baz, 3)
 }
+
+func TestExec(t *testing.T) {
+	t.Run("valid output", func(t *testing.T) {
+		cmd := exec.Command("echo", "# comment\nFOO=1\n\nexport BAR=a=b")
+		m, err := env.Exec(cmd)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, m, env.Map{"FOO": "1", "BAR": "a=b"})
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		cmd := exec.Command("echo", "FOO")
+		_, err := env.Exec(cmd)
+		assert.Equal[E](t, err.Error(), "env: line 1: invalid KEY=VALUE pair")
+	})
+
+	t.Run("command failed", func(t *testing.T) {
+		cmd := exec.Command("false")
+		_, err := env.Exec(cmd)
+		assert.AsErr[E](t, err, new(*exec.ExitError))
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	slow := sourceFunc(func(key string) (string, bool) {
+		time.Sleep(time.Second)
+		return "1", true
+	})
+
+	var cfg struct {
+		Foo int `env:"FOO"`
+	}
+
+	t.Run("fail open", func(t *testing.T) {
+		src := env.Timeout(slow, time.Millisecond, env.FailOpen)
+		err := env.Load(&cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Foo, 0)
+	})
+
+	t.Run("fail closed", func(t *testing.T) {
+		src := env.Timeout(slow, time.Millisecond, env.FailClosed)
+		err := env.Load(&cfg, &env.Options{Source: src})
+		assert.IsErr[E](t, err, env.ErrTimeout)
+	})
+
+	t.Run("in time", func(t *testing.T) {
+		src := env.Timeout(env.Map{"FOO": "1"}, time.Second, env.FailClosed)
+		err := env.Load(&cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Foo, 1)
+	})
+
+	t.Run("per load", func(t *testing.T) {
+		err := env.Load(&cfg, &env.Options{Source: slow, LoadTimeout: time.Millisecond})
+		assert.IsErr[E](t, err, env.ErrTimeout)
+	})
+}
+
+type sourceFunc func(key string) (string, bool)
+
+func (fn sourceFunc) LookupEnv(key string) (string, bool) { return fn(key) }
+
+func TestRetry(t *testing.T) {
+	errFlaky := errors.New("flaky")
+
+	var cfg struct {
+		Foo int `env:"FOO"`
+	}
+
+	t.Run("succeeds eventually", func(t *testing.T) {
+		src := &flakySource{failures: 2, err: errFlaky}
+		err := env.Load(&cfg, &env.Options{Source: env.Retry(src, 3, time.Millisecond)})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Foo, 1)
+		assert.Equal[E](t, src.calls, 3)
+	})
+
+	t.Run("all attempts failed", func(t *testing.T) {
+		src := &flakySource{failures: 5, err: errFlaky}
+		err := env.Load(&cfg, &env.Options{Source: env.Retry(src, 3, time.Millisecond)})
+		assert.IsErr[E](t, err, errFlaky)
+		assert.Equal[E](t, err.Error(), "env: looking up FOO: 3 attempts failed: flaky")
+	})
+
+	t.Run("invalid attempts", func(t *testing.T) {
+		retry := func() { env.Retry(env.Map{}, 0, 0) }
+		assert.Panics[E](t, retry, "env: attempts must be positive")
+	})
+}
+
+type flakySource struct {
+	failures int
+	calls    int
+	err      error
+}
+
+func (s *flakySource) LookupEnv(key string) (string, bool) {
+	value, ok, _ := s.LookupEnvErr(key)
+	return value, ok
+}
+
+func (s *flakySource) LookupEnvErr(string) (string, bool, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return "", false, s.err
+	}
+	return "1", true, nil
+}
+
+func TestAllowDeny(t *testing.T) {
+	m := env.Map{"APP_PORT": "8080", "DB_PASSWORD": "secret"}
+
+	var cfg struct {
+		Port     int    `env:"APP_PORT"`
+		Password string `env:"DB_PASSWORD"`
+	}
+
+	t.Run("allow", func(t *testing.T) {
+		cfg.Port, cfg.Password = 0, ""
+		err := env.Load(&cfg, &env.Options{Source: env.Allow(m, "APP_*")})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Port, 8080)
+		assert.Equal[E](t, cfg.Password, "")
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		cfg.Port, cfg.Password = 0, ""
+		err := env.Load(&cfg, &env.Options{Source: env.Deny(m, "*_PASSWORD")})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Port, 8080)
+		assert.Equal[E](t, cfg.Password, "")
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		allow := func() { env.Allow(m, "[") }
+		assert.Panics[E](t, allow, "env: invalid pattern `[`")
+	})
+}
+
+func TestSelect(t *testing.T) {
+	dev := env.Map{"PORT": "8080"}
+	prod := env.Map{"PORT": "80"}
+	sources := map[string]env.Source{"dev": dev, "prod": prod, "": dev}
+
+	t.Run("selected", func(t *testing.T) {
+		src, err := env.Select(env.Map{"APP_ENV": "prod"}, "APP_ENV", sources)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, src, env.Source(prod))
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		src, err := env.Select(env.Map{}, "APP_ENV", sources)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, src, env.Source(dev))
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := env.Select(env.Map{"APP_ENV": "test"}, "APP_ENV", sources)
+		assert.Equal[E](t, err.Error(), `env: no source for APP_ENV="test"`)
+	})
+}
+
+func TestMultiSource(t *testing.T) {
+	src := env.MultiSource(env.Map{"FOO": "1"}, env.Map{"FOO": "2", "BAR": "3"})
+
+	var cfg struct {
+		Foo int `env:"FOO"`
+		Bar int `env:"BAR"`
+	}
+	err := env.Load(&cfg, &env.Options{Source: src})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, cfg.Foo, 1)
+	assert.Equal[E](t, cfg.Bar, 3)
+	assert.Equal[E](t, src.(env.EnvironSource).Environ(), []string{"FOO=1", "BAR=3"})
+}
+
+func TestSQL(t *testing.T) {
+	sql.Register("env_settings", settingsDriver{"FOO": "1", "BAR": "2"})
+	db, err := sql.Open("env_settings", "")
+	assert.NoErr[F](t, err)
+	defer db.Close()
+
+	m, err := env.SQL(context.Background(), db, "SELECT name, value FROM settings")
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, m, env.Map{"FOO": "1", "BAR": "2"})
+}
+
+// settingsDriver is a read-only database/sql driver returning the map as a two-column table.
+type settingsDriver map[string]string
+
+func (d settingsDriver) Open(string) (driver.Conn, error) { return d, nil }
+
+func (d settingsDriver) Prepare(string) (driver.Stmt, error) { return d, nil }
+
+func (settingsDriver) Close() error { return nil }
+
+func (settingsDriver) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (settingsDriver) NumInput() int { return -1 }
+
+func (settingsDriver) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (d settingsDriver) Query([]driver.Value) (driver.Rows, error) {
+	rows := &settingsRows{}
+	for k, v := range d {
+		rows.pairs = append(rows.pairs, [2]string{k, v})
+	}
+	return rows, nil
+}
+
+type settingsRows struct {
+	pairs [][2]string
+}
+
+func (*settingsRows) Columns() []string { return []string{"name", "value"} }
+
+func (*settingsRows) Close() error { return nil }
+
+func (r *settingsRows) Next(dest []driver.Value) error {
+	if len(r.pairs) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.pairs[0][0], r.pairs[0][1]
+	r.pairs = r.pairs[1:]
+	return nil
+}
+
+func TestCached(t *testing.T) {
+	var fetches int
+	remote := map[string]string{"FOO": "1"}
+	src := env.Cached(func() (env.Map, error) {
+		fetches++
+		if remote == nil {
+			return nil, errors.New("unavailable")
+		}
+		m := make(env.Map, len(remote))
+		for k, v := range remote {
+			m[k] = v
+		}
+		return m, nil
+	})
+
+	var cfg struct {
+		Foo int `env:"FOO"`
+		Bar int `env:"BAR"`
+	}
+	err := env.Load(&cfg, &env.Options{Source: src})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, cfg.Foo, 1)
+	assert.Equal[E](t, fetches, 1)
+
+	remote["FOO"] = "2"
+	err = env.Load(&cfg, &env.Options{Source: src})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, cfg.Foo, 1)
+
+	src.Invalidate()
+	err = env.Load(&cfg, &env.Options{Source: src})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, cfg.Foo, 2)
+	assert.Equal[E](t, fetches, 2)
+
+	remote = nil
+	src.Invalidate()
+	err = env.Load(&cfg, &env.Options{Source: src})
+	assert.Equal[E](t, err.Error(), "env: fetching variables: unavailable")
+}
+
+func TestLookupFunc(t *testing.T) {
+	errDown := errors.New("down")
+	src := env.LookupFunc(func(key string) (string, bool, error) {
+		if key == "FOO" {
+			return "1", true, nil
+		}
+		return "", false, errDown
+	})
+
+	var cfg struct {
+		Foo int `env:"FOO"`
+		Bar int `env:"BAR"`
+	}
+	err := env.Load(&cfg, &env.Options{Source: src})
+	assert.IsErr[E](t, err, errDown)
+	assert.Equal[E](t, cfg.Foo, 1)
+}
+
+func TestPrefixes(t *testing.T) {
+	m := env.Map{"GLOBAL_DB_HOST": "db", "GLOBAL_DB_PORT": "5432", "BILLING_DB_PORT": "5433", "LOG_LEVEL": "info"}
+
+	var cfg struct {
+		Host     string `env:"DB_HOST"`
+		Port     int    `env:"DB_PORT"`
+		LogLevel string `env:"LOG_LEVEL"`
+	}
+	err := env.Load(&cfg, &env.Options{Source: env.Prefixes(m, "BILLING_", "GLOBAL_", "")})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, cfg.Host, "db")
+	assert.Equal[E](t, cfg.Port, 5433)
+	assert.Equal[E](t, cfg.LogLevel, "info")
+}
+
+func TestSlice(t *testing.T) {
+	m := env.Slice([]string{"FOO=1", "BAR=a=b", "FOO=2", "EMPTY=", "INVALID", "", "=C:=C:\\"})
+	assert.Equal[E](t, m, env.Map{"FOO": "2", "BAR": "a=b", "EMPTY": "", "=C:": "C:\\"})
+}
+
+type batchSource struct {
+	env.Map
+	batches [][]string
+}
+
+func (s *batchSource) LookupEnvs(keys []string) map[string]string {
+	s.batches = append(s.batches, keys)
+	m := make(map[string]string)
+	for _, key := range keys {
+		if value, ok := s.Map[key]; ok {
+			m[key] = value
+		}
+	}
+	return m
+}
+
+type slowBatchSource struct {
+	env.Map
+	delay time.Duration
+}
+
+func (s slowBatchSource) LookupEnvs(keys []string) map[string]string {
+	time.Sleep(s.delay)
+	m := make(map[string]string)
+	for _, key := range keys {
+		if value, ok := s.Map[key]; ok {
+			m[key] = value
+		}
+	}
+	return m
+}
+
+func TestBatchSource(t *testing.T) {
+	src := &batchSource{Map: env.Map{"HOST": "localhost", "PORT_EU": "8080", "ADDR": "${HOST}:80"}}
+
+	var lookups []string
+	hooks := env.Hooks{
+		Lookup: func(name string, _ bool, _ time.Duration, _ error) { lookups = append(lookups, name) },
+	}
+
+	var cfg struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+		Addr string `env:"ADDR,expand"`
+	}
+	err := env.Load(&cfg, &env.Options{Source: src, Suffix: "EU", Hooks: hooks})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, cfg.Host, "localhost")
+	assert.Equal[E](t, cfg.Port, 8080)
+	assert.Equal[E](t, cfg.Addr, "localhost:80")
+	assert.Equal[E](t, src.batches, [][]string{{"HOST_EU", "HOST", "PORT_EU", "PORT", "ADDR_EU", "ADDR"}})
+	assert.Equal[E](t, lookups, []string{"HOST_EU", "HOST", "PORT_EU", "ADDR_EU", "ADDR"})
+
+	t.Run("timeout", func(t *testing.T) {
+		src := slowBatchSource{Map: env.Map{"HOST": "localhost"}, delay: 500 * time.Millisecond}
+		err := env.Load(&cfg, &env.Options{Source: src, LoadTimeout: 10 * time.Millisecond})
+		assert.IsErr[E](t, err, env.ErrTimeout)
+	})
+}
+
+func TestTrace(t *testing.T) {
+	var trace []string
+	dotenv := env.Trace(env.Map{"PORT": "8080"}, func(key string, found bool, _ time.Duration, err error) {
+		trace = append(trace, key+"="+strconv.FormatBool(found))
+		assert.NoErr[E](t, err)
+	})
+
+	var cfg struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+	}
+	err := env.Load(&cfg, &env.Options{Source: env.MultiSource(env.Map{"HOST": "localhost"}, dotenv)})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, trace, []string{"PORT=true"})
+}
+
+type dbConfig struct {
+	Host string `env:"DB_HOST"`
+	Port int    `env:"DB_PORT"`
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	t.Run("environ", func(t *testing.T) {
+		src := env.CaseInsensitive(env.Map{"db_host": "localhost", "Db_Port": "5432"})
+		var cfg dbConfig
+		err := env.Load(&cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Host, "localhost")
+		assert.Equal[E](t, cfg.Port, 5432)
+	})
+
+	t.Run("lookup only", func(t *testing.T) {
+		m := env.Map{"db_host": "127.0.0.1", "Db_Port": "6543"}
+		src := env.CaseInsensitive(sourceFunc(m.LookupEnv))
+		var cfg dbConfig
+		err := env.Load(&cfg, &env.Options{Source: src})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Host, "127.0.0.1")
+		assert.Equal[E](t, cfg.Port, 0) // mixed case can't be matched without Environ.
+	})
+}
+
+func TestMapKeys(t *testing.T) {
+	m := env.Map{"db-host": "localhost", "db-port": "5432"}
+	src := env.MapKeys(m, func(key string) string {
+		return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+	})
+
+	var cfg struct {
+		Host string `env:"DB_HOST"`
+		Port int    `env:"DB_PORT"`
+	}
+	err := env.Load(&cfg, &env.Options{Source: src})
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, cfg.Host, "localhost")
+	assert.Equal[E](t, cfg.Port, 5432)
+}
+
+func TestRead(t *testing.T) {
+	m, err := env.Read(strings.NewReader("# comment\nexport FOO=1\n\nBAR=a=b\n"))
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, m, env.Map{"FOO": "1", "BAR": "a=b"})
+
+	_, err = env.Read(strings.NewReader("FOO"))
+	assert.Equal[E](t, err.Error(), "env: line 1: invalid KEY=VALUE pair")
+}
+
+func TestReadProfile(t *testing.T) {
+	const file = `LOG_LEVEL=info
+PORT=8080
+
+[dev]
+LOG_LEVEL=debug
+
+[prod]
+DB_HOST=db.internal
+`
+
+	m, err := env.ReadProfile(strings.NewReader(file), "dev")
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, m, env.Map{"LOG_LEVEL": "debug", "PORT": "8080"})
+
+	m, err = env.ReadProfile(strings.NewReader(file), "prod")
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, m, env.Map{"LOG_LEVEL": "info", "PORT": "8080", "DB_HOST": "db.internal"})
+}
+
+func TestBootstrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.env")
+	err := os.WriteFile(path, []byte("PORT=8080 # comment\nNAME=\"my app\"\n"), 0o600)
+	assert.NoErr[F](t, err)
+
+	t.Run("file", func(t *testing.T) {
+		src, err := env.Bootstrap(env.Map{"CONFIG_URL": "file://" + filepath.ToSlash(path)}, "CONFIG_URL", nil)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, src, env.Source(env.Map{"PORT": "8080", "NAME": "my app"}))
+	})
+
+	t.Run("custom scheme", func(t *testing.T) {
+		openers := map[string]env.SourceOpener{
+			"ssm": func(u *url.URL) (env.Source, error) {
+				return env.Map{"PATH": u.Path}, nil
+			},
+		}
+		src, err := env.Bootstrap(env.Map{"CONFIG_URL": "ssm:///prod/app/"}, "CONFIG_URL", openers)
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, src, env.Source(env.Map{"PATH": "/prod/app/"}))
+	})
+
+	t.Run("unknown scheme", func(t *testing.T) {
+		_, err := env.Bootstrap(env.Map{"CONFIG_URL": "s3://bucket/app.env"}, "CONFIG_URL", nil)
+		assert.Equal[E](t, err.Error(), `env: CONFIG_URL: no opener for scheme "s3"`)
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		_, err := env.Bootstrap(env.Map{}, "CONFIG_URL", nil)
+		assert.AsErr[E](t, err, new(*env.NotSetError))
+	})
+}
+
+func TestIgnoreMissing(t *testing.T) {
+	verify := func([]byte) error { return nil }
+
+	m, err := env.IgnoreMissing(env.VerifiedFile(filepath.Join(t.TempDir(), ".env.local"), verify))
+	assert.NoErr[F](t, err)
+	assert.Equal[E](t, m, env.Map{})
+
+	_, err = env.IgnoreMissing(env.VerifiedFile(t.TempDir(), verify))
+	assert.Equal[E](t, err != nil, true)
+}
+
+func TestVerifiedFile(t *testing.T) {
+	data := []byte("FOO=1 # comment\nBAR=\"a\nb\"\n")
+	path := filepath.Join(t.TempDir(), ".env")
+	err := os.WriteFile(path, data, 0o600)
+	assert.NoErr[F](t, err)
+
+	t.Run("ed25519", func(t *testing.T) {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		assert.NoErr[F](t, err)
+
+		m, err := env.VerifiedFile(path, env.VerifyEd25519(pub, ed25519.Sign(priv, data)))
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, m, env.Map{"FOO": "1", "BAR": "a\nb"})
+
+		_, err = env.VerifiedFile(path, env.VerifyEd25519(pub, ed25519.Sign(priv, []byte("FOO=2"))))
+		assert.IsErr[E](t, err, env.ErrVerification)
+	})
+
+	t.Run("sha256", func(t *testing.T) {
+		sum := sha256.Sum256(data)
+
+		m, err := env.VerifiedFile(path, env.VerifySHA256(hex.EncodeToString(sum[:])))
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, m, env.Map{"FOO": "1", "BAR": "a\nb"})
+
+		_, err = env.VerifiedFile(path, env.VerifySHA256("00"))
+		assert.IsErr[E](t, err, env.ErrVerification)
+	})
+}
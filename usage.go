@@ -1,10 +1,19 @@
 package env
 
 import (
+	"encoding/csv"
+	"flag"
 	"fmt"
+	"html/template"
 	"io"
+	"os"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"text/tabwriter"
+	"time"
 )
 
 // cache maps a struct type to the [Var] slice parsed from it.
@@ -17,7 +26,45 @@ import (
 //	env.Usage(&cfg, os.Stdout, nil) // 2. prints cfg.Port's default == 8080 (instead of 0)
 //
 // It also speeds up [Usage] a bit, since a struct is only parsed once.
-var cache = make(map[reflect.Type][]Var)
+//
+// The cache is bounded by cacheLimit entries, evicting the oldest entry when full.
+// It can be disabled per call with [Options.NoCache] and cleared with [ClearCache].
+var cache = struct {
+	sync.Mutex
+	vars  map[reflect.Type][]Var
+	order []reflect.Type // insertion order, used for eviction.
+}{vars: make(map[reflect.Type][]Var)}
+
+const cacheLimit = 1024
+
+// ClearCache removes all entries from the cache of parsed struct types.
+// It is useful for long-running programs that create struct types dynamically, e.g. with [reflect.StructOf].
+func ClearCache() {
+	cache.Lock()
+	defer cache.Unlock()
+	cache.vars = make(map[reflect.Type][]Var)
+	cache.order = nil
+}
+
+func cacheGet(t reflect.Type) ([]Var, bool) {
+	cache.Lock()
+	defer cache.Unlock()
+	vars, ok := cache.vars[t]
+	return vars, ok
+}
+
+func cachePut(t reflect.Type, vars []Var) {
+	cache.Lock()
+	defer cache.Unlock()
+	if _, ok := cache.vars[t]; !ok {
+		if len(cache.order) == cacheLimit {
+			delete(cache.vars, cache.order[0])
+			cache.order = cache.order[1:]
+		}
+		cache.order = append(cache.order, t)
+	}
+	cache.vars[t] = vars
+}
 
 // Var holds the information about the environment variable parsed from a struct field.
 type Var struct {
@@ -27,15 +74,63 @@ type Var struct {
 	Default  string       // The default value of the variable. Empty, if the variable is required.
 	Required bool         // True, if the variable is marked as required.
 	Expand   bool         // True, if the variable is marked to be expanded with [os.Expand].
+	Secret   bool         // True, if the variable is marked as secret.
+	Field    string       // The path of the struct field, e.g. DB.Host.
+	OneOf    []string     // The allowed values from the `oneof` option (if set).
 
 	structField   reflect.Value
+	conds         []condition // parsed from the `when` tags of the parent structs.
 	hasDefaultTag bool
+	plainString   bool // the field is of the builtin string type.
+	unescape      bool
+	expandHome    bool
+	maxLen        int // parsed from the `maxlen` tag, see [Options.MaxValueLen].
+	deprecated    bool
+	warnDefault   bool
+	json          bool
+	size          bool
+	parser        string          // the name of the parser from the `parser=NAME` option, see [Options.Parsers].
+	min, max      reflect.Value   // the bounds from the `min` and `max` options, if set.
+	pattern       *regexp.Regexp  // parsed from the `pattern` tag.
+	oneOf         []reflect.Value // the parsed values of OneOf.
+	source        string          // parsed from the `source` tag, see [Options.Sources].
+	refresh       time.Duration   // parsed from the `refresh` tag, see [Holder.StartRefresh].
+	dynamic       bool            // the field is a map of structs, see [Load].
+	remain        bool            // the field collects the unclaimed variables, see [Load].
+}
+
+// VisitVars calls fn for each environment variable declared in the given struct, in the order of the struct fields.
+// It allows building custom documentation, validation, or UI layers without relying on the [Usage] format.
+// The resolve function passed to fn looks up the current value of the variable in [Options.Source] on demand.
+// Iteration stops if fn returns false.
+// cfg must be a non-nil struct pointer, otherwise VisitVars panics.
+func VisitVars(cfg any, opts *Options, fn func(v Var, resolve func() (value string, ok bool, err error)) bool) {
+	pv := reflect.ValueOf(cfg)
+	if !structPtr(pv) {
+		panic("env: cfg must be a non-nil struct pointer")
+	}
+
+	opts = setDefaultOptions(opts)
+
+	for _, v := range expandDynamic(parseVars(pv.Elem(), opts), opts) {
+		v := v
+		resolve := func() (string, bool, error) {
+			if v.dynamic || v.remain {
+				return "", false, nil
+			}
+			return lookupEnv(routeOpts(opts, v), v.Name, v.Expand)
+		}
+		if !fn(v, resolve) {
+			return
+		}
+	}
 }
 
 // Usage writes a usage message documenting all defined environment variables to the given [io.Writer].
 // The caller must pass the same [Options] to both [Load] and [Usage], or nil.
 // An optional usage string can be added to environment variables with the `usage:"STRING"` struct tag.
-// The format of the message can be customized by implementing the Usage([]env.Var, io.Writer, *env.Options) method on the cfg's type.
+// The format of the message can be customized by implementing the Usage([]env.Var, io.Writer, *env.Options) method on the cfg's type,
+// or by setting [Options.UsageFunc].
 func Usage(cfg any, w io.Writer, opts *Options) {
 	pv := reflect.ValueOf(cfg)
 	if !structPtr(pv) {
@@ -44,13 +139,11 @@ func Usage(cfg any, w io.Writer, opts *Options) {
 
 	opts = setDefaultOptions(opts)
 
-	v := pv.Elem()
-	vars, ok := cache[v.Type()]
-	if !ok {
-		vars = parseVars(v, opts)
-	}
+	vars := usageVars(pv.Elem(), opts)
 
-	if u, ok := cfg.(interface {
+	if opts.UsageFunc != nil {
+		opts.UsageFunc(vars, w, opts)
+	} else if u, ok := cfg.(interface {
 		Usage([]Var, io.Writer, *Options)
 	}); ok {
 		u.Usage(vars, w, opts)
@@ -59,21 +152,150 @@ func Usage(cfg any, w io.Writer, opts *Options) {
 	}
 }
 
-func defaultUsage(vars []Var, w io.Writer, _ *Options) {
+// usageVars returns the variables to document, preferring the cached ones (see the cache comment).
+func usageVars(v reflect.Value, opts *Options) []Var {
+	var vars []Var
+	var ok bool
+	if !opts.NoCache {
+		vars, ok = cacheGet(v.Type())
+	}
+	if ok && opts.Hooks.CacheHit != nil {
+		opts.Hooks.CacheHit(v.Type())
+	}
+	if !ok {
+		vars = parseVars(v, opts)
+	}
+	return expandDynamic(vars, opts)
+}
+
+// FlagUsage sets the Usage function of the given [flag.FlagSet] to print the previous usage message
+// (or the default one, if not set) followed by the [Usage] message of the environment variables declared in cfg,
+// so that the -h output documents both flags and environment variables.
+func FlagUsage(fs *flag.FlagSet, cfg any, opts *Options) {
+	prev := fs.Usage
+	fs.Usage = func() {
+		if prev != nil {
+			prev()
+		} else {
+			fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+			fs.PrintDefaults()
+		}
+		fmt.Fprintf(fs.Output(), "Environment variables:\n")
+		Usage(cfg, fs.Output(), opts)
+	}
+}
+
+// AppendUsage appends an "Environment variables" section with the [Usage] message of cfg to the given help text.
+// It allows keeping the help of CLI frameworks in sync with the config struct without depending on them,
+// e.g. for cobra:
+//
+//	env.AppendUsage(&cmd.Long, &cfg, nil)
+func AppendUsage(help *string, cfg any, opts *Options) {
+	var sb strings.Builder
+	sb.WriteString(*help)
+	if *help != "" {
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("Environment variables:\n")
+	Usage(cfg, &sb, opts)
+	*help = sb.String()
+}
+
+// expandDynamic replaces each map of structs with the variables declared by the struct,
+// using <KEY> as the placeholder for the map key.
+func expandDynamic(vars []Var, opts *Options) []Var {
+	var expanded []Var
+	for _, v := range vars {
+		if !v.dynamic {
+			expanded = append(expanded, v)
+			continue
+		}
+		sep := autoSep(opts)
+		for _, ev := range expandDynamic(parseVars(reflect.New(v.Type.Elem()).Elem(), opts), opts) {
+			ev.Name = v.Name + sep + "<KEY>" + sep + ev.Name
+			ev.structField = reflect.Value{}
+			ev.dynamic = true
+			expanded = append(expanded, ev)
+		}
+	}
+	return expanded
+}
+
+func defaultUsage(vars []Var, w io.Writer, opts *Options) {
+	writeTable(vars, w, opts, false)
+}
+
+// ColorUsage writes the default usage message with ANSI colors: required variables are highlighted and defaults are dimmed.
+// It falls back to plain text if the NO_COLOR environment variable is set or w is not a terminal.
+// It can be used as [Options.UsageFunc].
+func ColorUsage(vars []Var, w io.Writer, opts *Options) {
+	writeTable(vars, w, opts, useColor(w))
+}
+
+// MissingUsage writes the default usage message only for the required variables that are not set in [Options.Source].
+// It is intended for the error path of [Load], so that the operator sees just what needs to be fixed.
+// It can be used as [Options.UsageFunc].
+func MissingUsage(vars []Var, w io.Writer, opts *Options) {
+	var missing []Var
+	for _, v := range vars {
+		if !v.Required || v.dynamic {
+			continue
+		}
+		if _, ok, _ := lookupEnv(routeOpts(opts, v), v.Name, false); !ok {
+			missing = append(missing, v)
+		}
+	}
+	writeTable(missing, w, opts, false)
+}
+
+func useColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// ANSI escape codes used by [ColorUsage].
+// The codes are of equal length to keep the table columns aligned.
+const (
+	ansiRed   = "\x1b[31m"
+	ansiDim   = "\x1b[02m"
+	ansiReset = "\x1b[0m"
+)
+
+func writeTable(vars []Var, w io.Writer, opts *Options, color bool) {
 	// TODO: use opts.SliceSep to parse slice values.
 
+	paint := func(s, code string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	msgs := messagesOf(opts)
+
 	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
 	defer tw.Flush()
 
 	for _, v := range vars {
 		fmt.Fprintf(tw, "\t%s\t%s", v.Name, v.Type)
+		var oneOf string
+		if len(v.OneOf) > 0 {
+			oneOf = " (" + msgs.OneOf + " " + strings.Join(v.OneOf, "|") + ")"
+		}
 		if v.Required {
-			fmt.Fprintf(tw, "\trequired")
+			fmt.Fprintf(tw, "\t%s", paint(msgs.Required+oneOf, ansiRed))
 		} else {
 			if v.Type.Kind() == reflect.String && v.Default == "" {
-				v.Default = "<empty>"
+				v.Default = msgs.Empty
 			}
-			fmt.Fprintf(tw, "\tdefault %s", v.Default)
+			fmt.Fprintf(tw, "\t%s", paint(msgs.Default+" "+v.Default+oneOf, ansiDim))
 		}
 		if v.Usage != "" {
 			fmt.Fprintf(tw, "\t%s", v.Usage)
@@ -81,3 +303,96 @@ func defaultUsage(vars []Var, w io.Writer, _ *Options) {
 		fmt.Fprintf(tw, "\n")
 	}
 }
+
+// ManUsage writes the ENVIRONMENT section of a man page in the roff format.
+// It can be used as [Options.UsageFunc] to keep man pages in sync with the config struct.
+func ManUsage(vars []Var, w io.Writer, opts *Options) {
+	msgs := messagesOf(opts)
+	fmt.Fprintf(w, ".SH ENVIRONMENT\n")
+	for _, v := range vars {
+		fmt.Fprintf(w, ".TP\n.B %s\n", roffEscape(v.Name))
+		desc := v.Type.String()
+		if v.Required {
+			desc += ", " + msgs.Required
+		} else {
+			desc += ", " + msgs.Default + " " + v.Default
+		}
+		if v.Usage != "" {
+			fmt.Fprintf(w, "%s (%s)\n", roffEscape(v.Usage), roffEscape(desc))
+		} else {
+			fmt.Fprintf(w, "(%s)\n", roffEscape(desc))
+		}
+	}
+}
+
+// CSVUsage writes the variables as CSV rows with a header, so that the configuration inventory can be imported into spreadsheets.
+// It can be used as [Options.UsageFunc].
+func CSVUsage(vars []Var, w io.Writer, opts *Options) {
+	_ = writeRecords(vars, w, ',', opts) // UsageFunc can't report errors, like the other usage writers.
+}
+
+// TSVUsage is like [CSVUsage], but separates the fields with tabs.
+// It can be used as [Options.UsageFunc].
+func TSVUsage(vars []Var, w io.Writer, opts *Options) {
+	_ = writeRecords(vars, w, '\t', opts)
+}
+
+// writeRecords writes the variables as records separated by comma, stopping at the first write error.
+func writeRecords(vars []Var, w io.Writer, comma rune, opts *Options) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	h := messagesOf(opts).Headers.or(csvHeaders)
+	if err := cw.Write([]string{h.Name, h.Type, h.Required, h.Default, h.Expand, h.Secret, h.Usage}); err != nil {
+		return err
+	}
+	for _, v := range vars {
+		err := cw.Write([]string{
+			v.Name,
+			v.Type.String(),
+			strconv.FormatBool(v.Required),
+			v.Default,
+			strconv.FormatBool(v.Expand),
+			strconv.FormatBool(v.Secret),
+			v.Usage,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// HTMLUsage writes the variables as an HTML table with an anchor per variable, e.g. #DB_HOST,
+// so that the generated reference can be embedded into documentation portals.
+// It can be used as [Options.UsageFunc].
+func HTMLUsage(vars []Var, w io.Writer, opts *Options) {
+	msgs := messagesOf(opts)
+	msgs.Headers = msgs.Headers.or(htmlHeaders)
+	_ = usageTemplate.Execute(w, struct {
+		Vars     []Var
+		Messages Messages
+	}{vars, msgs})
+}
+
+var usageTemplate = template.Must(template.New("").Parse(`<table>
+{{- with .Messages.Headers}}
+<tr><th>{{.Name}}</th><th>{{.Type}}</th><th>{{.Default}}</th><th>{{.Usage}}</th></tr>
+{{- end}}
+{{- range .Vars}}
+<tr id="{{.Name}}"><td><a href="#{{.Name}}"><code>{{.Name}}</code></a></td><td>{{.Type}}</td><td>{{if .Required}}{{$.Messages.Required}}{{else}}{{.Default}}{{end}}</td><td>{{.Usage}}</td></tr>
+{{- end}}
+</table>
+`))
+
+// roffEscape escapes the characters that have a special meaning in roff.
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
@@ -2,7 +2,13 @@ package env_test
 
 import (
 	"bytes"
+	"flag"
+	"fmt"
 	"io"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
 	"go-simpler.org/env"
@@ -31,6 +37,45 @@ func TestUsage(t *testing.T) {
 		assert.Equal[E](t, buf.String(), "  A_FOO  int  default 0\n")
 	})
 
+	t.Run("with Options.DefaultMode", func(t *testing.T) {
+		var buf bytes.Buffer
+		cfg := struct {
+			Bar int `env:"BAR"`
+		}{Bar: 1}
+		env.Usage(&cfg, &buf, &env.Options{DefaultMode: env.DefaultFromZero})
+		assert.Equal[E](t, buf.String(), "  BAR  int  default 0\n")
+	})
+
+	t.Run("allowed values", func(t *testing.T) {
+		var buf bytes.Buffer
+		var cfg struct {
+			Level  string `env:"LEVEL,oneof=debug|info" default:"info"`
+			Format string `env:"FORMAT,required,oneof=text|json"`
+		}
+		env.Usage(&cfg, &buf, nil)
+		assert.Equal[E](t, buf.String(), "  LEVEL   string  default info (one of debug|info)\n"+
+			"  FORMAT  string  required (one of text|json)\n")
+	})
+
+	t.Run("default file mode", func(t *testing.T) {
+		var buf bytes.Buffer
+		cfg := struct {
+			Dir os.FileMode `env:"DIR"`
+		}{Dir: os.ModeSticky | 0o777}
+		env.Usage(&cfg, &buf, nil)
+		assert.Equal[E](t, buf.String(), "  DIR  fs.FileMode  default 1777\n")
+	})
+
+	t.Run("defaults of big numbers", func(t *testing.T) {
+		var buf bytes.Buffer
+		var cfg struct {
+			Max big.Int `env:"MAX"`
+		}
+		cfg.Max.SetInt64(7)
+		env.Usage(&cfg, &buf, nil)
+		assert.Equal[E](t, buf.String(), "  MAX  big.Int  default 7\n")
+	})
+
 	t.Run("custom usage message", func(t *testing.T) {
 		var buf bytes.Buffer
 		var cfg Config
@@ -52,6 +97,35 @@ func TestUsage(t *testing.T) {
 		env.Usage(&cfg, &buf, nil)
 		assert.Equal[E](t, buf.String(), "  FOO  int  default 0\n")
 	})
+
+	t.Run("vars cache disabled", func(t *testing.T) {
+		m := env.Map{"QUX": "1"}
+
+		var cfg struct {
+			Qux int `env:"QUX"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m, NoCache: true})
+		assert.NoErr[F](t, err)
+
+		var buf bytes.Buffer
+		env.Usage(&cfg, &buf, &env.Options{NoCache: true})
+		assert.Equal[E](t, buf.String(), "  QUX  int  default 1\n")
+	})
+
+	t.Run("vars cache cleared", func(t *testing.T) {
+		m := env.Map{"QUUX": "1"}
+
+		var cfg struct {
+			Quux int `env:"QUUX"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		env.ClearCache()
+
+		var buf bytes.Buffer
+		env.Usage(&cfg, &buf, nil)
+		assert.Equal[E](t, buf.String(), "  QUUX  int  default 1\n")
+	})
 }
 
 type Config struct{}
@@ -59,3 +133,198 @@ type Config struct{}
 func (Config) Usage(_ []env.Var, w io.Writer, _ *env.Options) {
 	_, _ = w.Write([]byte("custom"))
 }
+
+func TestManUsage(t *testing.T) {
+	var buf bytes.Buffer
+	var cfg struct {
+		Host string `env:"DB_HOST,required" usage:"database host"`
+		Port int    `env:"DB_PORT" default:"-1"`
+	}
+	env.Usage(&cfg, &buf, &env.Options{UsageFunc: env.ManUsage})
+	assert.Equal[E](t, buf.String(), `.SH ENVIRONMENT
+.TP
+.B DB_HOST
+database host (string, required)
+.TP
+.B DB_PORT
+(int, default \-1)
+`)
+}
+
+func TestColorUsage(t *testing.T) {
+	var cfg struct {
+		Foo int `env:"FOO,required"`
+	}
+
+	t.Run("not a terminal", func(t *testing.T) {
+		var buf bytes.Buffer
+		env.Usage(&cfg, &buf, &env.Options{UsageFunc: env.ColorUsage})
+		assert.Equal[E](t, buf.String(), "  FOO  int  required\n")
+	})
+}
+
+func TestMissingUsage(t *testing.T) {
+	m := env.Map{"FOO": "1"}
+
+	var buf bytes.Buffer
+	var cfg struct {
+		Foo int `env:"FOO,required"`
+		Bar int `env:"BAR,required" usage:"bar"`
+		Baz int `env:"BAZ"`
+	}
+	env.Usage(&cfg, &buf, &env.Options{Source: m, UsageFunc: env.MissingUsage})
+	assert.Equal[E](t, buf.String(), "  BAR  int  required  bar\n")
+
+	t.Run("routing and suffix", func(t *testing.T) {
+		var cfg struct {
+			Host     string `env:"DB_HOST,required"`
+			Password string `env:"DB_PASSWORD,required" source:"vault"`
+			User     string `env:"DB_USER,required"`
+		}
+		opts := &env.Options{
+			Source:    env.Map{"DB_HOST_EU": "db"},
+			Sources:   map[string]env.Source{"vault": env.Map{"DB_PASSWORD": "secret"}},
+			Suffix:    "EU",
+			UsageFunc: env.MissingUsage,
+		}
+
+		var buf bytes.Buffer
+		env.Usage(&cfg, &buf, opts)
+		assert.Equal[E](t, buf.String(), "  DB_USER  string  required\n")
+	})
+}
+
+func TestVisitVars(t *testing.T) {
+	m := env.Map{"FOO": "1", "BAR": "2"}
+
+	var cfg struct {
+		Foo int `env:"FOO"`
+		Bar int `env:"BAR,required"`
+		Baz int `env:"BAZ"`
+	}
+
+	var got []string
+	env.VisitVars(&cfg, &env.Options{Source: m}, func(v env.Var, resolve func() (string, bool, error)) bool {
+		value, ok, err := resolve()
+		assert.NoErr[E](t, err)
+		got = append(got, fmt.Sprintf("%s=%s (%t)", v.Name, value, ok))
+		return v.Name != "BAR"
+	})
+	assert.Equal[E](t, got, []string{"FOO=1 (true)", "BAR=2 (true)"})
+}
+
+func TestFlagUsage(t *testing.T) {
+	var buf bytes.Buffer
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&buf)
+	fs.Bool("debug", false, "enable debug mode")
+
+	var cfg struct {
+		Port int `env:"PORT" default:"8080" usage:"http port"`
+	}
+	env.FlagUsage(fs, &cfg, nil)
+	fs.Usage()
+	assert.Equal[E](t, buf.String(), `Usage of app:
+  -debug
+    	enable debug mode
+Environment variables:
+  PORT  int  default 8080  http port
+`)
+}
+
+func TestAppendUsage(t *testing.T) {
+	var cfg struct {
+		Port int `env:"PORT" default:"8080"`
+	}
+
+	help := "Run the server."
+	env.AppendUsage(&help, &cfg, nil)
+	assert.Equal[E](t, help, "Run the server.\n\nEnvironment variables:\n  PORT  int  default 8080\n")
+
+	help = ""
+	env.AppendUsage(&help, &cfg, nil)
+	assert.Equal[E](t, help, "Environment variables:\n  PORT  int  default 8080\n")
+}
+
+func TestCSVUsage(t *testing.T) {
+	var cfg struct {
+		Host string `env:"CSV_HOST,required" usage:"database host, primary"`
+		Port int    `env:"CSV_PORT" default:"5432"`
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		env.Usage(&cfg, &buf, &env.Options{UsageFunc: env.CSVUsage})
+		assert.Equal[E](t, buf.String(), `name,type,required,default,expand,secret,usage
+CSV_HOST,string,true,,false,false,"database host, primary"
+CSV_PORT,int,false,5432,false,false,
+`)
+	})
+
+	t.Run("tsv", func(t *testing.T) {
+		var buf bytes.Buffer
+		env.Usage(&cfg, &buf, &env.Options{UsageFunc: env.TSVUsage})
+		assert.Equal[E](t, buf.String(), "name\ttype\trequired\tdefault\texpand\tsecret\tusage\n"+
+			"CSV_HOST\tstring\ttrue\t\tfalse\tfalse\tdatabase host, primary\n"+
+			"CSV_PORT\tint\tfalse\t5432\tfalse\tfalse\t\n")
+	})
+}
+
+func TestHTMLUsage(t *testing.T) {
+	var buf bytes.Buffer
+	var cfg struct {
+		Host string `env:"HTML_HOST,required" usage:"<database> host"`
+		Port int    `env:"HTML_PORT" default:"5432"`
+	}
+	env.Usage(&cfg, &buf, &env.Options{UsageFunc: env.HTMLUsage})
+	assert.Equal[E](t, buf.String(), `<table>
+<tr><th>Name</th><th>Type</th><th>Default</th><th>Usage</th></tr>
+<tr id="HTML_HOST"><td><a href="#HTML_HOST"><code>HTML_HOST</code></a></td><td>string</td><td>required</td><td>&lt;database&gt; host</td></tr>
+<tr id="HTML_PORT"><td><a href="#HTML_PORT"><code>HTML_PORT</code></a></td><td>int</td><td>5432</td><td></td></tr>
+</table>
+`)
+}
+
+func TestMessages(t *testing.T) {
+	msgs := env.Messages{
+		Required: "obligatoire",
+		Default:  "défaut",
+		NotSet: func(names []string) string {
+			return "env: non défini: " + strings.Join(names, ", ")
+		},
+	}
+
+	var cfg struct {
+		Host string `env:"MSG_HOST,required"`
+		Port int    `env:"MSG_PORT" default:"80"`
+		Name string `env:"MSG_NAME"`
+	}
+
+	var buf bytes.Buffer
+	env.Usage(&cfg, &buf, &env.Options{Messages: msgs})
+	assert.Equal[E](t, buf.String(), `  MSG_HOST  string  obligatoire
+  MSG_PORT  int     défaut 80
+  MSG_NAME  string  défaut <empty>
+`)
+
+	err := env.Load(&cfg, &env.Options{Source: env.Map{}, Messages: msgs})
+	assert.Equal[E](t, err.Error(), "env: non défini: MSG_HOST")
+
+	msgs.Headers = env.Headers{Name: "Nom", Default: "Défaut", Usage: "Utilisation"}
+
+	buf.Reset()
+	env.Usage(&cfg, &buf, &env.Options{Messages: msgs, UsageFunc: env.HTMLUsage})
+	assert.Equal[E](t, strings.Contains(buf.String(), "<tr><th>Nom</th><th>Type</th><th>Défaut</th><th>Utilisation</th></tr>"), true)
+	assert.Equal[E](t, strings.Contains(buf.String(), "<td>obligatoire</td>"), true)
+
+	buf.Reset()
+	env.Usage(&cfg, &buf, &env.Options{Messages: msgs, UsageFunc: env.CSVUsage})
+	assert.Equal[E](t, strings.SplitN(buf.String(), "\n", 2)[0], "Nom,type,required,Défaut,expand,secret,Utilisation")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/html")
+	env.Handler(&cfg, &env.Options{Messages: msgs}).ServeHTTP(w, r)
+	assert.Equal[E](t, strings.Contains(w.Body.String(), "<tr><th>Nom</th><th>Type</th><th>Value</th><th>Défaut</th><th>Utilisation</th></tr>"), true)
+	assert.Equal[E](t, strings.Contains(w.Body.String(), "<td>obligatoire</td>"), true)
+}
@@ -0,0 +1,130 @@
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// integer reports whether the type (or the type it points to) is an integer, e.g. for the `size` option.
+func integer(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// numeric reports whether the type (or the type it points to) is an integer or a float, e.g. for the `min` and `max` options.
+func numeric(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return integer(t) || t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+}
+
+// stringly reports whether the type is a string, a pointer to a string, or a slice of strings, e.g. for the `pattern` tag.
+func stringly(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String
+}
+
+// scalar reports whether the type (or the type it points to) is a string, a bool, or a number, e.g. for the `oneof` option.
+func scalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return numeric(t) || t.Kind() == reflect.String || t.Kind() == reflect.Bool
+}
+
+// parseOption parses the value of the `min`, `max`, or `oneof` option the same way as the value of the field.
+func parseOption(t reflect.Type, s string, size bool, opts *Options) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	bound := Var{structField: reflect.New(t).Elem(), size: size}
+	if err := setVar(bound, s, opts); err != nil {
+		return reflect.Value{}, err
+	}
+	return bound.structField, nil
+}
+
+// validate checks the value of the struct field against the constraints declared in the struct tags.
+// The returned error is wrapped in [ValidationError] by the caller.
+func validate(v Var) error {
+	field := v.structField
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	value := func() string {
+		if v.Secret {
+			return "value" // the secret values are not included in the errors.
+		}
+		return formatValue(field)
+	}
+	if v.min.IsValid() && compare(field, v.min) < 0 {
+		return fmt.Errorf("%s is less than min %s", value(), formatValue(v.min))
+	}
+	if v.max.IsValid() && compare(field, v.max) > 0 {
+		return fmt.Errorf("%s is greater than max %s", value(), formatValue(v.max))
+	}
+	if len(v.oneOf) > 0 {
+		found := false
+		for _, allowed := range v.oneOf {
+			if field.Interface() == allowed.Interface() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value must be one of %s", strings.Join(v.OneOf, ", "))
+		}
+	}
+	if v.pattern != nil {
+		// the values are not included in the error, since they may be secret.
+		values := []reflect.Value{field}
+		if field.Kind() == reflect.Slice {
+			values = values[:0]
+			for i := 0; i < field.Len(); i++ {
+				values = append(values, field.Index(i))
+			}
+		}
+		for _, value := range values {
+			if !v.pattern.MatchString(value.String()) {
+				return fmt.Errorf("value does not match pattern `%s`", v.pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// compare compares two numeric values of the same type.
+func compare(a, b reflect.Value) int {
+	var less, greater bool
+	switch {
+	case kindOf(a, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64):
+		less, greater = a.Int() < b.Int(), a.Int() > b.Int()
+	case kindOf(a, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64):
+		less, greater = a.Uint() < b.Uint(), a.Uint() > b.Uint()
+	default:
+		less, greater = a.Float() < b.Float(), a.Float() > b.Float()
+	}
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,129 @@
+package env_test
+
+import (
+	"testing"
+	"time"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestValidation(t *testing.T) {
+	t.Run("min and max", func(t *testing.T) {
+		m := env.Map{"PORT": "8080", "RATIO": "0.5", "TIMEOUT": "5s", "CACHE": "1GiB"}
+
+		var cfg struct {
+			Port    int           `env:"PORT,min=1,max=65535"`
+			Ratio   float64       `env:"RATIO,min=0,max=1"`
+			Timeout time.Duration `env:"TIMEOUT,max=1m"`
+			Cache   *int64        `env:"CACHE,size,min=1MB"`
+			Workers uint          `env:"WORKERS,min=1" default:"4"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Port, 8080)
+		assert.Equal[E](t, *cfg.Cache, 1<<30)
+
+		m["PORT"] = "70000"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		var validationErr *env.ValidationError
+		assert.AsErr[F](t, err, &validationErr)
+		assert.Equal[E](t, validationErr.Field, "Port")
+		assert.Equal[E](t, err.Error(), "env: PORT: 70000 is greater than max 65535")
+
+		m["PORT"] = "80"
+		m["TIMEOUT"] = "2m"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.Equal[E](t, err.Error(), "env: TIMEOUT: 2m0s is greater than max 1m0s")
+
+		m["TIMEOUT"] = "5s"
+		m["CACHE"] = "1kB"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.Equal[E](t, err.Error(), "env: CACHE: 1000 is less than min 1000000")
+	})
+
+	t.Run("secret min", func(t *testing.T) {
+		var cfg struct {
+			PIN int `env:"PIN,secret,min=1000"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: env.Map{"PIN": "42"}})
+		assert.Equal[E](t, err.Error(), "env: PIN: value is less than min 1000")
+	})
+
+	t.Run("pattern", func(t *testing.T) {
+		m := env.Map{"SERVICE": "billing-api", "REGIONS": "eu-west us-east"}
+
+		var cfg struct {
+			Service string   `env:"SERVICE" pattern:"^[a-z-]+$"`
+			Regions []string `env:"REGIONS" pattern:"^[a-z]+-[a-z]+$"`
+			Owner   *string  `env:"OWNER" pattern:"^@"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.Owner, nil)
+
+		m["REGIONS"] = "eu-west global"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		var validationErr *env.ValidationError
+		assert.AsErr[F](t, err, &validationErr)
+		assert.Equal[E](t, err.Error(), "env: REGIONS: value does not match pattern `^[a-z]+-[a-z]+$`")
+
+		var invalid struct {
+			Port int `env:"PORT" pattern:"^[0-9]+$"`
+		}
+		load := func() { _ = env.Load(&invalid, nil) }
+		assert.Panics[E](t, load, "env: `pattern` can't be used with type `int`")
+
+		var broken struct {
+			Name string `env:"NAME" pattern:"[a-z"`
+		}
+		load = func() { _ = env.Load(&broken, nil) }
+		assert.Panics[E](t, load, "env: invalid pattern `[a-z`: error parsing regexp: missing closing ]: `[a-z`")
+	})
+
+	t.Run("oneof", func(t *testing.T) {
+		m := env.Map{"LOG_LEVEL": "info", "WORKERS": "4"}
+
+		var cfg struct {
+			LogLevel string `env:"LOG_LEVEL,oneof=debug|info|warn|error"`
+			Workers  int    `env:"WORKERS,oneof=1|2|4|8"`
+			Format   string `env:"FORMAT,oneof=text|json" default:"text"`
+		}
+		err := env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+		assert.Equal[E](t, cfg.LogLevel, "info")
+		assert.Equal[E](t, cfg.Format, "text")
+
+		m["LOG_LEVEL"] = "verbose"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		var validationErr *env.ValidationError
+		assert.AsErr[F](t, err, &validationErr)
+		assert.Equal[E](t, err.Error(), "env: LOG_LEVEL: value must be one of debug, info, warn, error")
+
+		m["LOG_LEVEL"] = "info"
+		m["WORKERS"] = "04"
+		err = env.Load(&cfg, &env.Options{Source: m})
+		assert.NoErr[F](t, err)
+
+		var invalid struct {
+			Workers int `env:"WORKERS,oneof=1|two"`
+		}
+		load := func() { _ = env.Load(&invalid, nil) }
+		assert.Panics[E](t, load, "env: invalid oneof value `two` of WORKERS: parsing int: strconv.ParseInt: parsing \"two\": invalid syntax")
+	})
+
+	t.Run("invalid bounds", func(t *testing.T) {
+		var cfg struct {
+			Port int `env:"PORT,min=one"`
+		}
+		load := func() { _ = env.Load(&cfg, nil) }
+		assert.Panics[E](t, load, "env: invalid min `one` of PORT: parsing int: strconv.ParseInt: parsing \"one\": invalid syntax")
+
+		var str struct {
+			Name string `env:"NAME,max=10"`
+		}
+		load = func() { _ = env.Load(&str, nil) }
+		assert.Panics[E](t, load, "env: `min` and `max` can't be used with type `string`")
+	})
+}
@@ -0,0 +1,150 @@
+package env
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Change describes a change of an environment variable detected by a [Watcher].
+type Change struct {
+	Name string // The name of the variable.
+	Old  string // The previous value, or an empty string if the variable was not set.
+	New  string // The current value, or an empty string if the variable is not set anymore.
+}
+
+// Watcher periodically re-resolves the environment variables declared in a struct and notifies the subscribers about changes.
+// It does not modify the struct itself, so it is safe to use the struct concurrently.
+type Watcher struct {
+	vars []Var
+	opts *Options
+
+	mu     sync.Mutex
+	values map[string]string
+	subs   map[int]func([]Change)
+	nextID int
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// Watch starts watching the environment variables declared in the given struct for changes.
+// The variables are re-resolved every interval; if interval is 0, they are only re-resolved by [Watcher.Poll].
+// The caller must call [Watcher.Stop] to release the resources.
+// cfg must be a non-nil struct pointer, otherwise Watch panics.
+func Watch(cfg any, interval time.Duration, opts *Options) *Watcher {
+	pv := reflect.ValueOf(cfg)
+	if !structPtr(pv) {
+		panic("env: cfg must be a non-nil struct pointer")
+	}
+
+	opts = setDefaultOptions(opts)
+
+	w := &Watcher{
+		vars: parseVars(pv.Elem(), opts),
+		opts: opts,
+		subs: make(map[int]func([]Change)),
+		stop: make(chan struct{}),
+	}
+	w.values = w.resolve()
+
+	if interval > 0 {
+		go w.run(interval)
+	}
+
+	return w
+}
+
+// Subscribe registers fn to be called with the changes detected by the watcher.
+// A panic in fn is recovered and reported to [Hooks.SubscriberPanic], so it does not affect other subscribers.
+// The returned function unregisters fn.
+func (w *Watcher) Subscribe(fn func(changes []Change)) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	w.subs[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subs, id)
+	}
+}
+
+// Poll re-resolves the variables immediately, notifies the subscribers, and returns the detected changes.
+func (w *Watcher) Poll() []Change {
+	values := w.resolve()
+
+	w.mu.Lock()
+	var changes []Change
+	for _, v := range w.vars {
+		if old, cur := w.values[v.Name], values[v.Name]; old != cur {
+			changes = append(changes, Change{Name: v.Name, Old: old, New: cur})
+		}
+	}
+	w.values = values
+	subs := make([]func([]Change), 0, len(w.subs))
+	for id := 0; id < w.nextID; id++ {
+		if fn, ok := w.subs[id]; ok {
+			subs = append(subs, fn)
+		}
+	}
+	w.mu.Unlock()
+
+	if len(changes) > 0 {
+		for _, fn := range subs {
+			w.notify(fn, changes)
+		}
+	}
+
+	return changes
+}
+
+// Stop stops watching. It is safe to call Stop multiple times.
+func (w *Watcher) Stop() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+func (w *Watcher) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.Poll()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *Watcher) notify(fn func([]Change), changes []Change) {
+	defer func() {
+		if r := recover(); r != nil && w.opts.Hooks.SubscriberPanic != nil {
+			w.opts.Hooks.SubscriberPanic(r)
+		}
+	}()
+	fn(changes)
+}
+
+// resolve looks up the current values of the variables.
+// If a lookup fails, the previous value is kept.
+func (w *Watcher) resolve() map[string]string {
+	values := make(map[string]string, len(w.vars))
+	for _, v := range w.vars {
+		if v.dynamic || v.remain {
+			continue
+		}
+		value, _, err := lookupEnv(routeOpts(w.opts, v), v.Name, v.Expand)
+		if err != nil {
+			w.mu.Lock()
+			value = w.values[v.Name]
+			w.mu.Unlock()
+		}
+		values[v.Name] = value
+	}
+	return values
+}
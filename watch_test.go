@@ -0,0 +1,51 @@
+package env_test
+
+import (
+	"testing"
+
+	"go-simpler.org/env"
+	"go-simpler.org/env/internal/assert"
+	. "go-simpler.org/env/internal/assert/EF"
+)
+
+func TestWatcher_Subscribe(t *testing.T) {
+	m := env.Map{"LOG_LEVEL": "info", "RATE_LIMIT": "10"}
+
+	var recovered any
+	opts := &env.Options{
+		Source: m,
+		Hooks:  env.Hooks{SubscriberPanic: func(r any) { recovered = r }},
+	}
+
+	var cfg struct {
+		LogLevel  string `env:"LOG_LEVEL"`
+		RateLimit int    `env:"RATE_LIMIT"`
+	}
+	w := env.Watch(&cfg, 0, opts)
+	defer w.Stop()
+
+	var got1, got2 []env.Change
+	w.Subscribe(func([]env.Change) { panic("boom") })
+	w.Subscribe(func(changes []env.Change) { got1 = append(got1, changes...) })
+	unsubscribe := w.Subscribe(func(changes []env.Change) { got2 = append(got2, changes...) })
+
+	assert.Equal[E](t, len(w.Poll()), 0)
+
+	m["LOG_LEVEL"] = "debug"
+	delete(m, "RATE_LIMIT")
+	changes := w.Poll()
+	want := []env.Change{
+		{Name: "LOG_LEVEL", Old: "info", New: "debug"},
+		{Name: "RATE_LIMIT", Old: "10", New: ""},
+	}
+	assert.Equal[E](t, changes, want)
+	assert.Equal[E](t, got1, want)
+	assert.Equal[E](t, got2, want)
+	assert.Equal[E](t, recovered, any("boom"))
+
+	unsubscribe()
+	m["LOG_LEVEL"] = "warn"
+	w.Poll()
+	assert.Equal[E](t, len(got1), 3)
+	assert.Equal[E](t, len(got2), 2)
+}